@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// runCommand runs the command named by the first argument instead of the
+// server, returning false if there's none. The only command is
+//
+//	chirpy create-admin -email EMAIL
+//
+// which makes the first admin, creating the user if needed. The password is
+// read from ADMIN_PASSWORD if it's set, or else from the first line of stdin,
+// so it doesn't end up in the shell history or the process list.
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	switch args[0] {
+	case "create-admin":
+		createAdmin(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		os.Exit(2)
+	}
+	return true
+}
+
+func createAdmin(args []string) {
+	flags := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	email := flags.String("email", "", "email of the admin")
+	flags.Parse(args)
+
+	if *email == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	password, err := readAdminPassword()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading password:", err)
+		os.Exit(1)
+	}
+	if password == "" {
+		fmt.Fprintln(os.Stderr, "No password given in ADMIN_PASSWORD or on stdin")
+		os.Exit(2)
+	}
+
+	s.InitDB()
+	s.InitSearch()
+	s.InitAudit()
+	admin, err := s.BootstrapAdmin(*email, password)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error creating admin:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("User %d (%s) is now an admin\n", admin.ID, admin.Email)
+}
+
+// readAdminPassword returns ADMIN_PASSWORD if it's set, or else the first line
+// of stdin
+func readAdminPassword() (string, error) {
+	if password, ok := os.LookupEnv("ADMIN_PASSWORD"); ok {
+		return password, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -7,4 +7,5 @@ require (
 	github.com/golang-jwt/jwt/v5 v5.2.0
 	github.com/joho/godotenv v1.5.1
 	golang.org/x/crypto v0.18.0
+	golang.org/x/text v0.14.0
 )
@@ -4,13 +4,18 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/wipdev-tech/chirpy/internal/search"
+	"github.com/wipdev-tech/chirpy/internal/service"
+	"github.com/wipdev-tech/chirpy/internal/trends"
 )
 
 // reqUserData is used by handlers to decode user data from incoming HTTP
@@ -18,6 +23,35 @@ import (
 type reqUserData struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Handle   string `json:"handle"`
+}
+
+// viewerID returns the ID of the user making the request, or 0 if the request
+// isn't authenticated. Read endpoints work without authentication but tailor
+// their responses to the viewer when there is one.
+func viewerID(r *http.Request) int {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		return 0
+	}
+	return userID
+}
+
+// writeSuspension responds with 403 and the reason and end of the suspension
+// if the error is a suspension, reporting whether it was
+func writeSuspension(w http.ResponseWriter, err error) bool {
+	var suspension *service.SuspensionError
+	if !errors.As(err, &suspension) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusForbidden)
+	err = json.NewEncoder(w).Encode(suspension)
+	if err != nil {
+		panic(err)
+	}
+	return true
 }
 
 func handleHealth(w http.ResponseWriter, _ *http.Request) {
@@ -61,7 +95,7 @@ func handleGetChirps(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if authorIDParam == "" {
-		chirps := s.GetChirps(sortAsc)
+		chirps := s.GetChirps(viewerID(r), sortAsc)
 		w.WriteHeader(http.StatusOK)
 		err := json.NewEncoder(w).Encode(chirps)
 		if err != nil {
@@ -75,7 +109,7 @@ func handleGetChirps(w http.ResponseWriter, r *http.Request) {
 		panic(err)
 	}
 
-	chirps := s.GetChirpsByAuthor(authorID, sortAsc)
+	chirps := s.GetChirpsByAuthor(viewerID(r), authorID, sortAsc)
 	w.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(w).Encode(chirps)
 	if err != nil {
@@ -83,9 +117,108 @@ func handleGetChirps(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func handleGetHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	sortAsc := r.URL.Query().Get("sort") != "desc"
+	tag := chi.URLParam(r, "tag")
+
+	chirps := s.GetChirpsByHashtag(viewerID(r), tag, sortAsc)
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(chirps)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleGetTrends(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		l, err := strconv.Atoi(limitParam)
+		if err != nil || l < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+
+	allTrends := s.GetTrends(limit)
+	windowParam := r.URL.Query().Get("window")
+	if windowParam != "" {
+		windowTrends, ok := allTrends[windowParam]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		allTrends = map[string][]trends.Trend{windowParam: windowTrends}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(allTrends)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := search.Query{
+		Text:       query.Get("q"),
+		SortRecent: query.Get("sort") == "recent",
+	}
+	if strings.TrimSpace(q.Text) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if authorIDParam := query.Get("author_id"); authorIDParam != "" {
+		q.AuthorID, err = strconv.Atoi(authorIDParam)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if sinceParam := query.Get("since"); sinceParam != "" {
+		q.Since, err = parseDate(sinceParam)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if untilParam := query.Get("until"); untilParam != "" {
+		q.Until, err = parseDate(untilParam)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := s.Search(viewerID(r), q)
+	if err != nil {
+		fmt.Println("Error searching:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(results)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// parseDate parses a query parameter given either as a full RFC 3339
+// timestamp or as a date (YYYY-MM-DD)
+func parseDate(param string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, param)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, param)
+}
+
 func handleGetChirp(w http.ResponseWriter, r *http.Request) {
 	chirpID := chi.URLParam(r, "chirpID")
-	chirp, ok := s.GetChirp(chirpID)
+	chirp, ok := s.GetChirp(viewerID(r), chirpID)
 
 	if ok {
 		w.WriteHeader(http.StatusOK)
@@ -101,7 +234,12 @@ func handleGetChirp(w http.ResponseWriter, r *http.Request) {
 
 func handleCreateChirp(w http.ResponseWriter, r *http.Request) {
 	type msg struct {
-		Body string
+		Body       string
+		MediaIDs   []int            `json:"media_ids"`
+		Visibility string           `json:"visibility"`
+		Draft      bool             `json:"draft"`
+		PublishAt  *time.Time       `json:"publish_at"`
+		Poll       *service.NewPoll `json:"poll"`
 	}
 	inMsg := msg{}
 	err := json.NewDecoder(r.Body).Decode(&inMsg)
@@ -117,29 +255,157 @@ func handleCreateChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(inMsg.Body) <= 140 {
-		newChirp, err := s.CreateChirp(authorID, inMsg.Body)
+	// Drafts and scheduled chirps are saved for later instead, and can't carry
+	// polls
+	if inMsg.Draft || inMsg.PublishAt != nil {
+		if inMsg.Poll != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		draft, err := s.SaveDraft(authorID, inMsg.Body, inMsg.MediaIDs, inMsg.Visibility, inMsg.PublishAt)
+		if isInvalidDraft(err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
 		if err != nil {
-			fmt.Println("Error creating new chirp")
+			fmt.Println("Error saving draft:", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
 		w.WriteHeader(http.StatusCreated)
-		err = json.NewEncoder(w).Encode(newChirp)
+		err = json.NewEncoder(w).Encode(draft)
 		if err != nil {
 			panic(err)
 		}
 		return
 	}
 
-	w.WriteHeader(http.StatusBadRequest)
+	newChirp, err := s.CreateChirp(authorID, inMsg.Body, inMsg.MediaIDs, inMsg.Visibility, inMsg.Poll)
+	if errors.Is(err, service.ErrInvalidMedia) ||
+		errors.Is(err, service.ErrInvalidVisibility) ||
+		errors.Is(err, service.ErrInvalidPoll) ||
+		errors.Is(err, service.ErrRejectedContent) ||
+		errors.Is(err, service.ErrSpam) ||
+		errors.Is(err, service.ErrChirpTooLong) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error creating new chirp")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Chirps held for review are saved but not posted yet
+	if newChirp.Held {
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	err = json.NewEncoder(w).Encode(newChirp)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleRechirp(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	authorID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	chirpID := chi.URLParam(r, "chirpID")
+	newChirp, err := s.Rechirp(authorID, chirpID)
+	if errors.Is(err, service.ErrChirpNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrBlocked) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrNotRechirpable) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrAlreadyRechirped) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error rechirping:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(newChirp)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleQuoteChirp(w http.ResponseWriter, r *http.Request) {
+	type msg struct {
+		Body string
+	}
+	inMsg := msg{}
+	err := json.NewDecoder(r.Body).Decode(&inMsg)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	authorID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	chirpID := chi.URLParam(r, "chirpID")
+	newChirp, err := s.QuoteChirp(authorID, chirpID, inMsg.Body)
+	if errors.Is(err, service.ErrChirpNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrBlocked) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrRejectedContent) ||
+		errors.Is(err, service.ErrSpam) ||
+		errors.Is(err, service.ErrChirpTooLong) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error quoting chirp:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Chirps held for review are saved but not posted yet
+	if newChirp.Held {
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	err = json.NewEncoder(w).Encode(newChirp)
+	if err != nil {
+		panic(err)
+	}
 }
 
 func handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	type OutUsr struct {
 		ID          int    `json:"id"`
 		Email       string `json:"email"`
+		Handle      string `json:"handle"`
 		IsChirpyRed bool   `json:"is_chirpy_red"`
 	}
 
@@ -150,7 +416,19 @@ func handleCreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbUser, err := s.CreateUser(inUsr.Email, inUsr.Password)
+	dbUser, err := s.CreateUser(inUsr.Email, inUsr.Password, inUsr.Handle)
+	if errors.Is(err, service.ErrInvalidEmail) || errors.Is(err, service.ErrWeakPassword) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidHandle) || errors.Is(err, service.ErrReservedHandle) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrHandleTaken) || errors.Is(err, service.ErrEmailTaken) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
 	if err != nil {
 		fmt.Println("Error creating new user", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -160,6 +438,7 @@ func handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	outUsr := OutUsr{
 		ID:          dbUser.ID,
 		Email:       dbUser.Email,
+		Handle:      dbUser.Handle,
 		IsChirpyRed: dbUser.IsChirpyRed,
 	}
 	w.WriteHeader(http.StatusCreated)
@@ -177,11 +456,14 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := s.Login(inUsr.Email, inUsr.Password)
+	user, err := s.Login(inUsr.Email, inUsr.Password, s.ClientIP(r))
 	if err != nil && err.Error() == "user doesn't exist" {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
+	if writeSuspension(w, err) {
+		return
+	}
 
 	if err != nil {
 		fmt.Println("Error logging in:", err)
@@ -197,8 +479,14 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleUpdateUser(w http.ResponseWriter, r *http.Request) {
-	inUsr := reqUserData{}
-	err := json.NewDecoder(r.Body).Decode(&inUsr)
+	type inUpdate struct {
+		Email           *string `json:"email"`
+		Password        *string `json:"password"`
+		CurrentPassword string  `json:"current_password"`
+	}
+
+	in := inUpdate{}
+	err := json.NewDecoder(r.Body).Decode(&in)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
@@ -211,8 +499,26 @@ func handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	newUser, err := s.UpdateUser(userID, inUsr.Email, inUsr.Password)
+	update := service.UserUpdate{
+		Email:           in.Email,
+		Password:        in.Password,
+		CurrentPassword: in.CurrentPassword,
+	}
+	newUser, err := s.UpdateUser(userID, update, s.ClientIP(r))
+	if errors.Is(err, service.ErrWrongPassword) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidEmail) || errors.Is(err, service.ErrWeakPassword) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrEmailTaken) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
 	if err != nil {
+		fmt.Println("Error updating user:", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -226,6 +532,9 @@ func handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 func handleRefresh(w http.ResponseWriter, r *http.Request) {
 	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
 	userID, err := s.AuthorizeRefresh(bearer)
+	if writeSuspension(w, err) {
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
@@ -246,13 +555,13 @@ func handleRefresh(w http.ResponseWriter, r *http.Request) {
 
 func handleRevoke(w http.ResponseWriter, r *http.Request) {
 	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
-	_, err := s.AuthorizeRefresh(bearer)
-	if err != nil {
+	userID, err := s.AuthorizeRefresh(bearer)
+	if err != nil && !errors.Is(err, service.ErrSuspended) {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
-	err = s.Revoke(bearer)
+	err = s.Revoke(userID, bearer, s.ClientIP(r))
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -261,7 +570,17 @@ func handleRevoke(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func handleDeleteChirp(w http.ResponseWriter, r *http.Request) {
+func handleEditChirp(w http.ResponseWriter, r *http.Request) {
+	type msg struct {
+		Body string
+	}
+	inMsg := msg{}
+	err := json.NewDecoder(r.Body).Decode(&inMsg)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
 	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
 	authorID, err := s.AuthorizeUser(bearer)
 	if err != nil {
@@ -269,19 +588,75 @@ func handleDeleteChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	chirpID := chi.URLParam(r, "chirpID")
-	chirp, ok := s.GetChirp(chirpID)
-	if !ok {
+	chirp, err := s.EditChirp(authorID, chi.URLParam(r, "chirpID"), inMsg.Body)
+	if errors.Is(err, service.ErrChirpNotFound) {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
-
-	if authorID != chirp.AuthorID {
+	if errors.Is(err, service.ErrNotAuthor) || errors.Is(err, service.ErrEditWindowClosed) {
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
+	if errors.Is(err, service.ErrNotEditable) ||
+		errors.Is(err, service.ErrRejectedContent) ||
+		errors.Is(err, service.ErrSpam) ||
+		errors.Is(err, service.ErrChirpTooLong) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error editing chirp:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 
-	err = s.DeleteChirp(chirpID)
+	// Edits held for review hide the chirp until it's approved
+	if chirp.Held {
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	err = json.NewEncoder(w).Encode(chirp)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleGetChirpHistory(w http.ResponseWriter, r *http.Request) {
+	history, err := s.GetChirpHistory(viewerID(r), chi.URLParam(r, "chirpID"))
+	if errors.Is(err, service.ErrChirpNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(history)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleDeleteChirp(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	err = s.DeleteChirp(userID, chi.URLParam(r, "chirpID"), s.ClientIP(r))
+	if errors.Is(err, service.ErrChirpNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrNotAuthor) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -311,7 +686,7 @@ func handlePolkaWebhook(w http.ResponseWriter, r *http.Request) {
 
 	if inEvent.Event == "user.upgraded" {
 		userID := inEvent.Data.UserID
-		err := s.UpgradeChirpyRed(userID)
+		err := s.UpgradeChirpyRed(userID, s.ClientIP(r))
 		if err != nil {
 			fmt.Println(err)
 		}
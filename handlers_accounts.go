@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/service"
+)
+
+func handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	type inDelete struct {
+		Password string `json:"password"`
+	}
+
+	in := inDelete{}
+	err := json.NewDecoder(r.Body).Decode(&in)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	err = s.DeleteUser(userID, in.Password, s.ClientIP(r))
+	if errors.Is(err, service.ErrWrongPassword) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error deleting user:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleExportUser(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	archive, err := s.ExportUser(userID, s.ClientIP(r))
+	if err != nil {
+		fmt.Println("Error exporting user data:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("chirpy-export-%d-%s.zip", userID, time.Now().UTC().Format("20060102"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(archive)
+	if err != nil {
+		fmt.Println("Error writing export:", err)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wipdev-tech/chirpy/internal/audit"
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/service"
+)
+
+func handleSetRole(w http.ResponseWriter, r *http.Request) {
+	type inRole struct {
+		Role string `json:"role"`
+	}
+
+	in := inRole{}
+	err := json.NewDecoder(r.Body).Decode(&in)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	user, err := s.SetRole(viewerID(r), userID, in.Role, s.ClientIP(r))
+	if errors.Is(err, service.ErrUserNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidRole) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error setting role:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(user)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleSuspendUser(w http.ResponseWriter, r *http.Request) {
+	type inSuspension struct {
+		Reason string `json:"reason"`
+		// Duration is how long the suspension lasts, e.g. "72h".
+		// Suspensions without one last until they're lifted.
+		Duration string `json:"duration"`
+	}
+
+	in := inSuspension{}
+	err := json.NewDecoder(r.Body).Decode(&in)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var duration time.Duration
+	if in.Duration != "" {
+		duration, err = time.ParseDuration(in.Duration)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	action, err := s.SuspendUser(viewerID(r), userID, in.Reason, duration, s.ClientIP(r))
+	writeModAction(w, action, err)
+}
+
+func handleUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	action, err := s.UnsuspendUser(viewerID(r), userID, s.ClientIP(r))
+	writeModAction(w, action, err)
+}
+
+func handleShadowBan(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	action, err := s.SetShadowBanned(viewerID(r), userID, r.Method == http.MethodPost, s.ClientIP(r))
+	writeModAction(w, action, err)
+}
+
+// writeModAction responds with the moderation action taken on an account, or
+// with the status code for the error
+func writeModAction(w http.ResponseWriter, action db.ModAction, err error) {
+	if errors.Is(err, service.ErrUserNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidModAction) || errors.Is(err, service.ErrNotSuspended) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error moderating user:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(action)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	f := audit.Filter{
+		Event:  query.Get("event"),
+		Target: query.Get("target"),
+		Limit:  100,
+	}
+
+	var err error
+	if v := query.Get("actor_id"); v != "" {
+		f.ActorID, err = strconv.Atoi(v)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		f.Limit, err = strconv.Atoi(v)
+		if err != nil || f.Limit < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("since"); v != "" {
+		f.Since, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("until"); v != "" {
+		f.Until, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := s.GetAuditLog(viewerID(r), f)
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error reading audit log:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(entries)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleVerifyAuditLog(w http.ResponseWriter, r *http.Request) {
+	res, err := s.VerifyAuditLog(viewerID(r))
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error verifying audit log:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(res)
+	if err != nil {
+		panic(err)
+	}
+}
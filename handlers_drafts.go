@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wipdev-tech/chirpy/internal/service"
+)
+
+// isInvalidDraft reports whether saving or publishing a draft failed because
+// of its contents
+func isInvalidDraft(err error) bool {
+	return errors.Is(err, service.ErrInvalidMedia) ||
+		errors.Is(err, service.ErrRejectedContent) ||
+		errors.Is(err, service.ErrSpam) ||
+		errors.Is(err, service.ErrChirpTooLong) ||
+		errors.Is(err, service.ErrInvalidPublishTime) ||
+		errors.Is(err, service.ErrInvalidVisibility)
+}
+
+func handleGetDrafts(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	drafts, err := s.GetDrafts(userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(drafts)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleUpdateDraft(w http.ResponseWriter, r *http.Request) {
+	type msg struct {
+		Body       string
+		MediaIDs   []int      `json:"media_ids"`
+		Visibility string     `json:"visibility"`
+		PublishAt  *time.Time `json:"publish_at"`
+	}
+	inMsg := msg{}
+	err := json.NewDecoder(r.Body).Decode(&inMsg)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	draftID, err := strconv.Atoi(chi.URLParam(r, "draftID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	draft, err := s.UpdateDraft(userID, draftID, inMsg.Body, inMsg.MediaIDs, inMsg.Visibility, inMsg.PublishAt)
+	if errors.Is(err, service.ErrDraftNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if isInvalidDraft(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error updating draft:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(draft)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleDeleteDraft(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	draftID, err := strconv.Atoi(chi.URLParam(r, "draftID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	err = s.DeleteDraft(userID, draftID)
+	if errors.Is(err, service.ErrDraftNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handlePublishDraft(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	draftID, err := strconv.Atoi(chi.URLParam(r, "draftID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	chirp, err := s.PublishDraft(userID, draftID)
+	if errors.Is(err, service.ErrDraftNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if isInvalidDraft(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error publishing draft:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if chirp.Held {
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	err = json.NewEncoder(w).Encode(chirp)
+	if err != nil {
+		panic(err)
+	}
+}
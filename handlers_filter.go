@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wipdev-tech/chirpy/internal/service"
+)
+
+func handleGetFilterRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := s.GetFilterRules(viewerID(r))
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(rules)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleSetFilterRule(w http.ResponseWriter, r *http.Request) {
+	type msg struct {
+		Action string `json:"action"`
+	}
+	inMsg := msg{}
+	err := json.NewDecoder(r.Body).Decode(&inMsg)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rule, err := s.SetFilterRule(viewerID(r), chi.URLParam(r, "word"), inMsg.Action, s.ClientIP(r))
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidFilterRule) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error saving filter rule:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(rule)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleDeleteFilterRule(w http.ResponseWriter, r *http.Request) {
+	err := s.DeleteFilterRule(viewerID(r), chi.URLParam(r, "word"), s.ClientIP(r))
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrFilterRuleNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error deleting filter rule:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleGetContentFlags(w http.ResponseWriter, r *http.Request) {
+	flags, err := s.GetContentFlags(viewerID(r))
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(flags)
+	if err != nil {
+		panic(err)
+	}
+}
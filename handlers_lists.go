@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wipdev-tech/chirpy/internal/service"
+)
+
+func handleBookmark(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	err = s.Bookmark(userID, chi.URLParam(r, "chirpID"))
+	if errors.Is(err, service.ErrChirpNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error bookmarking chirp:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleUnbookmark(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	err = s.Unbookmark(userID, chi.URLParam(r, "chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleGetBookmarks(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	chirps, err := s.GetBookmarks(userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(chirps)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// reqList is the request body for creating and updating lists
+type reqList struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Private     bool   `json:"private"`
+}
+
+// writeListError maps list errors to HTTP statuses, reporting whether there
+// was an error
+func writeListError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrListNotFound), errors.Is(err, service.ErrUserNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, service.ErrNotListOwner), errors.Is(err, service.ErrBlocked):
+		w.WriteHeader(http.StatusForbidden)
+	case errors.Is(err, service.ErrInvalidList):
+		w.WriteHeader(http.StatusBadRequest)
+	default:
+		fmt.Println("Error handling list:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	return true
+}
+
+func handleCreateList(w http.ResponseWriter, r *http.Request) {
+	inList := reqList{}
+	err := json.NewDecoder(r.Body).Decode(&inList)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	list, err := s.CreateList(userID, inList.Name, inList.Description, inList.Private)
+	if writeListError(w, err) {
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(list)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// handleGetLists lists the public lists of the user given by the owner_id
+// query parameter, or the authenticated user's own lists without it
+func handleGetLists(w http.ResponseWriter, r *http.Request) {
+	viewer := viewerID(r)
+	ownerID := viewer
+	if param := r.URL.Query().Get("owner_id"); param != "" {
+		id, err := strconv.Atoi(param)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ownerID = id
+	}
+	if ownerID == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	lists, err := s.GetLists(viewer, ownerID)
+	if writeListError(w, err) {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(lists)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleGetList(w http.ResponseWriter, r *http.Request) {
+	list, err := s.GetList(viewerID(r), chi.URLParam(r, "listID"))
+	if writeListError(w, err) {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(list)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleUpdateList(w http.ResponseWriter, r *http.Request) {
+	inList := reqList{}
+	err := json.NewDecoder(r.Body).Decode(&inList)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	list, err := s.UpdateList(userID, chi.URLParam(r, "listID"), inList.Name, inList.Description, inList.Private)
+	if writeListError(w, err) {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(list)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleDeleteList(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	err = s.DeleteList(userID, chi.URLParam(r, "listID"))
+	if writeListError(w, err) {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleGetListMembers(w http.ResponseWriter, r *http.Request) {
+	members, err := s.GetListMembers(viewerID(r), chi.URLParam(r, "listID"))
+	if writeListError(w, err) {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(members)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleAddListMember(w http.ResponseWriter, r *http.Request) {
+	type msg struct {
+		UserID int `json:"user_id"`
+	}
+	inMsg := msg{}
+	err := json.NewDecoder(r.Body).Decode(&inMsg)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	list, err := s.AddListMember(userID, chi.URLParam(r, "listID"), inMsg.UserID)
+	if writeListError(w, err) {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(list)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleRemoveListMember(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	memberID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	list, err := s.RemoveListMember(userID, chi.URLParam(r, "listID"), memberID)
+	if writeListError(w, err) {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(list)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleGetListTimeline(w http.ResponseWriter, r *http.Request) {
+	chirps, err := s.GetListTimeline(viewerID(r), chi.URLParam(r, "listID"))
+	if writeListError(w, err) {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(chirps)
+	if err != nil {
+		panic(err)
+	}
+}
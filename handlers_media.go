@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wipdev-tech/chirpy/internal/media"
+	"github.com/wipdev-tech/chirpy/internal/service"
+)
+
+// readImageUpload reads the image in the given field of a multipart form. If
+// the upload is missing or too large, the error status is written and false is
+// returned.
+func readImageUpload(w http.ResponseWriter, r *http.Request, field string) ([]byte, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, media.MaxImageSize+1024)
+	file, _, err := r.FormFile(field)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+	defer file.Close()
+
+	image, err := io.ReadAll(io.LimitReader(file, media.MaxImageSize+1))
+	if err != nil || len(image) > media.MaxImageSize {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+	return image, true
+}
+
+func handleUploadMedia(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	image, ok := readImageUpload(w, r, "file")
+	if !ok {
+		return
+	}
+
+	uploaded, err := s.UploadMedia(userID, image)
+	if errors.Is(err, service.ErrInvalidImage) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error uploading media:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(uploaded)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleGetMedia(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "*")
+	data, err := s.GetMediaBlob(viewerID(r), key)
+	if errors.Is(err, service.ErrMediaNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error getting media:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Media may only be visible to some users, so shared caches must not
+	// keep it
+	w.Header().Set("Cache-Control", "private")
+	http.ServeContent(w, r, path.Base(key), time.Time{}, bytes.NewReader(data))
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wipdev-tech/chirpy/internal/graphemes"
+	"github.com/wipdev-tech/chirpy/internal/service"
+)
+
+// maxMessageLen is the longest direct message allowed, in grapheme clusters
+const maxMessageLen = 1000
+
+func handleCreateConversation(w http.ResponseWriter, r *http.Request) {
+	type inConv struct {
+		ParticipantIDs []int `json:"participant_ids"`
+	}
+
+	in := inConv{}
+	err := json.NewDecoder(r.Body).Decode(&in)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	conv, err := s.StartConversation(userID, in.ParticipantIDs)
+	if errors.Is(err, service.ErrInvalidParticipants) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrUserNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrDMNotAllowed) || errors.Is(err, service.ErrBlocked) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error creating conversation:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(conv)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleGetConversations(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	convs, err := s.GetConversations(userID)
+	if err != nil {
+		fmt.Println("Error getting conversations:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(convs)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleSendMessage(w http.ResponseWriter, r *http.Request) {
+	type msg struct {
+		Body string `json:"body"`
+	}
+	inMsg := msg{}
+	err := json.NewDecoder(r.Body).Decode(&inMsg)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if strings.TrimSpace(inMsg.Body) == "" || graphemes.Count(inMsg.Body) > maxMessageLen {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	convID, err := strconv.Atoi(chi.URLParam(r, "conversationID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	newMsg, err := s.SendMessage(userID, convID, inMsg.Body)
+	if errors.Is(err, service.ErrConversationNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrBlocked) || errors.Is(err, service.ErrDMNotAllowed) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error sending message:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(newMsg)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleGetMessages(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	convID, err := strconv.Atoi(chi.URLParam(r, "conversationID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	beforeID, limit := 0, 50
+	if beforeParam := r.URL.Query().Get("before"); beforeParam != "" {
+		beforeID, err = strconv.Atoi(beforeParam)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > 100 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	msgs, err := s.GetMessages(userID, convID, beforeID, limit)
+	if errors.Is(err, service.ErrConversationNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error getting messages:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(msgs)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleReadConversation(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	convID, err := strconv.Atoi(chi.URLParam(r, "conversationID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	conv, err := s.MarkConversationRead(userID, convID)
+	if errors.Is(err, service.ErrConversationNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error marking conversation read:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(conv)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleUpdateDMSettings(w http.ResponseWriter, r *http.Request) {
+	type settings struct {
+		OpenDMs bool `json:"open_dms"`
+	}
+
+	in := settings{}
+	err := json.NewDecoder(r.Body).Decode(&in)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	err = s.SetOpenDMs(userID, in.OpenDMs)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(in)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/wipdev-tech/chirpy/internal/service"
+)
+
+func handleGetNotifications(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	page, limit := 1, 20
+	if pageParam := r.URL.Query().Get("page"); pageParam != "" {
+		page, err = strconv.Atoi(pageParam)
+		if err != nil || page < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > 100 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	notifications, err := s.GetNotifications(userID, page, limit)
+	if err != nil {
+		fmt.Println("Error getting notifications:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(notifications)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleReadNotifications(w http.ResponseWriter, r *http.Request) {
+	type inRead struct {
+		IDs []int `json:"ids"`
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// An empty body marks all notifications as read
+	in := inRead{}
+	if r.ContentLength != 0 {
+		err = json.NewDecoder(r.Body).Decode(&in)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = s.MarkNotificationsRead(userID, in.IDs)
+	if err != nil {
+		fmt.Println("Error marking notifications read:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleGetNotificationPrefs(w http.ResponseWriter, r *http.Request) {
+	type outPrefs struct {
+		Muted []string `json:"muted"`
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	muted, err := s.GetMutedNotifications(userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(outPrefs{Muted: muted})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleUpdateNotificationPrefs(w http.ResponseWriter, r *http.Request) {
+	type prefs struct {
+		Muted []string `json:"muted"`
+	}
+
+	inPrefs := prefs{}
+	err := json.NewDecoder(r.Body).Decode(&inPrefs)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	muted, err := s.SetMutedNotifications(userID, inPrefs.Muted)
+	if errors.Is(err, service.ErrInvalidNotificationType) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(prefs{Muted: muted})
+	if err != nil {
+		panic(err)
+	}
+}
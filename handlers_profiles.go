@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wipdev-tech/chirpy/internal/service"
+)
+
+func handleGetProfile(w http.ResponseWriter, r *http.Request) {
+	handle := strings.TrimPrefix(chi.URLParam(r, "handle"), "@")
+	profile, err := s.GetProfile(handle)
+	if errors.Is(err, service.ErrUserNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error getting profile:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(profile)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	type inProfile struct {
+		Handle      string `json:"handle"`
+		DisplayName string `json:"display_name"`
+		Bio         string `json:"bio"`
+	}
+
+	in := inProfile{}
+	err := json.NewDecoder(r.Body).Decode(&in)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := s.UpdateProfile(userID, in.Handle, in.DisplayName, in.Bio)
+	if errors.Is(err, service.ErrInvalidHandle) ||
+		errors.Is(err, service.ErrReservedHandle) ||
+		errors.Is(err, service.ErrInvalidProfile) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrHandleTaken) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error updating profile:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(profile)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleUpdatePrivacy(w http.ResponseWriter, r *http.Request) {
+	type inPrivacy struct {
+		Protected bool `json:"protected"`
+	}
+
+	in := inPrivacy{}
+	err := json.NewDecoder(r.Body).Decode(&in)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	err = s.SetProtected(userID, in.Protected)
+	if err != nil {
+		fmt.Println("Error updating privacy:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleUploadAvatar(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	image, ok := readImageUpload(w, r, "avatar")
+	if !ok {
+		return
+	}
+
+	author, err := s.SetAvatar(userID, image)
+	if errors.Is(err, service.ErrInvalidImage) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error setting avatar:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(author)
+	if err != nil {
+		panic(err)
+	}
+}
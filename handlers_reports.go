@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/service"
+)
+
+func handleCreateReport(w http.ResponseWriter, r *http.Request) {
+	type inReport struct {
+		ChirpID int    `json:"chirp_id"`
+		UserID  int    `json:"user_id"`
+		Reason  string `json:"reason"`
+	}
+
+	in := inReport{}
+	err := json.NewDecoder(r.Body).Decode(&in)
+	if err != nil || (in.ChirpID == 0) == (in.UserID == 0) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	reporterID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var report db.Report
+	if in.ChirpID != 0 {
+		report, err = s.ReportChirp(reporterID, in.ChirpID, in.Reason)
+	} else {
+		report, err = s.ReportUser(reporterID, in.UserID, in.Reason)
+	}
+	if errors.Is(err, service.ErrChirpNotFound) || errors.Is(err, service.ErrUserNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidReport) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrAlreadyReported) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error creating report:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(report)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleGetReports(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if !r.URL.Query().Has("status") {
+		status = db.ReportOpen
+	}
+
+	reports, err := s.GetReports(viewerID(r), status)
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidReportStatus) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(reports)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleGetReport(w http.ResponseWriter, r *http.Request) {
+	reportID, err := strconv.Atoi(chi.URLParam(r, "reportID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	report, err := s.GetReport(viewerID(r), reportID)
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrReportNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(report)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleModerateReport(w http.ResponseWriter, r *http.Request) {
+	type inAction struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+		// Duration is how long a suspension lasts, e.g. "72h". Suspensions
+		// without one last until they're lifted.
+		Duration string `json:"duration"`
+	}
+
+	in := inAction{}
+	err := json.NewDecoder(r.Body).Decode(&in)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var duration time.Duration
+	if in.Duration != "" {
+		duration, err = time.ParseDuration(in.Duration)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	reportID, err := strconv.Atoi(chi.URLParam(r, "reportID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	report, err := s.Moderate(viewerID(r), reportID, in.Action, in.Note, duration, s.ClientIP(r))
+	if errors.Is(err, service.ErrReportNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidModAction) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrReportClosed) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error moderating report:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(report)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleGetModActions(w http.ResponseWriter, r *http.Request) {
+	actions, err := s.GetModActions(viewerID(r))
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(actions)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wipdev-tech/chirpy/internal/service"
+)
+
+func handleGetSpamConfig(w http.ResponseWriter, r *http.Request) {
+	config, err := s.GetSpamConfig(viewerID(r))
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(config)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleGetSpamVerdicts(w http.ResponseWriter, r *http.Request) {
+	var reviewed *bool
+	if r.URL.Query().Has("reviewed") {
+		b, err := strconv.ParseBool(r.URL.Query().Get("reviewed"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reviewed = &b
+	}
+
+	verdicts, err := s.GetSpamVerdicts(viewerID(r), r.URL.Query().Get("verdict"), reviewed)
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidVerdict) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(verdicts)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleGetSpamVerdict(w http.ResponseWriter, r *http.Request) {
+	verdictID, err := strconv.Atoi(chi.URLParam(r, "verdictID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	verdict, err := s.GetSpamVerdict(viewerID(r), verdictID)
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrVerdictNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(verdict)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func handleReviewSpamVerdict(w http.ResponseWriter, r *http.Request) {
+	type inReview struct {
+		Action string `json:"action"`
+	}
+
+	in := inReview{}
+	err := json.NewDecoder(r.Body).Decode(&in)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	verdictID, err := strconv.Atoi(chi.URLParam(r, "verdictID"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	verdict, err := s.ReviewSpamVerdict(viewerID(r), verdictID, in.Action, s.ClientIP(r))
+	if errors.Is(err, service.ErrForbidden) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrVerdictNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidSpamReview) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrVerdictReviewed) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error reviewing spam verdict:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(verdict)
+	if err != nil {
+		panic(err)
+	}
+}
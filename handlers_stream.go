@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/stream"
+)
+
+// heartbeatInterval is how often idle streams are pinged to keep connections
+// (and any proxies in between) from timing out
+const heartbeatInterval = 15 * time.Second
+
+// streamAuth authorizes a streaming request. Browsers can't set headers on
+// EventSource and WebSocket connections, so the access token may also be
+// passed in the "token" query parameter.
+func streamAuth(r *http.Request) (int, error) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	if bearer == "" {
+		bearer = r.URL.Query().Get("token")
+	}
+	return s.AuthorizeUser(bearer)
+}
+
+// lastEventID reads the ID of the last event the client received, from the
+// Last-Event-ID header sent by reconnecting EventSources or from the
+// "last_event_id" query parameter
+func lastEventID(r *http.Request) uint64 {
+	param := r.Header.Get("Last-Event-ID")
+	if param == "" {
+		param = r.URL.Query().Get("last_event_id")
+	}
+	id, _ := strconv.ParseUint(param, 10, 64)
+	return id
+}
+
+func handleStreamSSE(w http.ResponseWriter, r *http.Request) {
+	userID, err := streamAuth(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// Writes to a stalled client time out instead of blocking forever
+	rc := http.NewResponseController(w)
+	setDeadline := func() error {
+		return rc.SetWriteDeadline(time.Now().Add(stream.WriteTimeout))
+	}
+
+	sub, missed := s.Subscribe(userID, lastEventID(r))
+	defer s.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	if setDeadline() != nil {
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	writeEvent := func(e stream.Event) error {
+		data, err := json.Marshal(e.Data)
+		if err != nil {
+			return err
+		}
+		err = setDeadline()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+		flusher.Flush()
+		return err
+	}
+
+	for _, e := range missed {
+		if writeEvent(e) != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case e := <-sub.Events:
+			if writeEvent(e) != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if setDeadline() != nil {
+				return
+			}
+			_, err := fmt.Fprint(w, ": heartbeat\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-sub.Done:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func handleStreamWS(w http.ResponseWriter, r *http.Request) {
+	userID, err := streamAuth(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := stream.Upgrade(w, r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+
+	sub, missed := s.Subscribe(userID, lastEventID(r))
+	defer s.Unsubscribe(sub)
+
+	writeEvent := func(e stream.Event) error {
+		msg, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		return conn.WriteText(msg)
+	}
+
+	for _, e := range missed {
+		if writeEvent(e) != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case e := <-sub.Events:
+			if writeEvent(e) != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if conn.Ping() != nil {
+				return
+			}
+		case <-sub.Done:
+			return
+		case <-conn.Closed():
+			return
+		}
+	}
+}
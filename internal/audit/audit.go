@@ -0,0 +1,199 @@
+// Package audit keeps an append-only log of security-relevant events. Entries
+// are written as JSON lines to a file that is only ever appended to, and each
+// entry carries the hash of the one before it, so editing or removing an
+// entry breaks the chain from that point on.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrTampered is returned by Verify when the hash chain is broken
+var ErrTampered = errors.New("audit log has been tampered with")
+
+// Entry is a single audited event. ActorID is the user who caused it (0 if
+// unknown, e.g. for failed logins or webhooks), Target what it was done to,
+// e.g. "user:3" or "chirp:12", and Details any event-specific data.
+type Entry struct {
+	Seq      int               `json:"seq"`
+	Time     time.Time         `json:"time"`
+	Event    string            `json:"event"`
+	ActorID  int               `json:"actor_id"`
+	IP       string            `json:"ip"`
+	Target   string            `json:"target,omitempty"`
+	Details  map[string]string `json:"details,omitempty"`
+	PrevHash string            `json:"prev_hash"`
+	Hash     string            `json:"hash"`
+}
+
+// Filter narrows down a query. Zero fields don't filter, and a Limit of 0
+// returns every matching entry.
+type Filter struct {
+	Event   string
+	ActorID int
+	Target  string
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+}
+
+// Log is an audit log file. It is safe for concurrent use.
+type Log struct {
+	mux      sync.Mutex
+	file     *os.File
+	path     string
+	lastSeq  int
+	lastHash string
+}
+
+// Open opens the audit log at the given path, creating it if it doesn't
+// exist, and picks up the chain where the last entry left off
+func Open(path string) (*Log, error) {
+	l := &Log{path: path}
+	entries, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		l.lastSeq, l.lastHash = last.Seq, last.Hash
+	}
+
+	l.file, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Append adds an entry to the log, filling in its sequence number, time and
+// hashes
+func (l *Log) Append(e Entry) (Entry, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	e.Seq = l.lastSeq + 1
+	e.Time = time.Now().UTC()
+	e.PrevHash = l.lastHash
+	e.Hash = hash(e)
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+	_, err = l.file.Write(append(line, '\n'))
+	if err != nil {
+		return e, err
+	}
+	err = l.file.Sync()
+	if err != nil {
+		return e, err
+	}
+
+	l.lastSeq, l.lastHash = e.Seq, e.Hash
+	return e, nil
+}
+
+// Query returns the entries matching the filter, newest first
+func (l *Log) Query(f Filter) ([]Entry, error) {
+	l.mux.Lock()
+	entries, err := l.read()
+	l.mux.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	out := []Entry{}
+	slices.Reverse(entries)
+	for _, e := range entries {
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+		if (f.Event != "" && e.Event != f.Event) ||
+			(f.ActorID != 0 && e.ActorID != f.ActorID) ||
+			(f.Target != "" && e.Target != f.Target) ||
+			(!f.Since.IsZero() && e.Time.Before(f.Since)) ||
+			(!f.Until.IsZero() && e.Time.After(f.Until)) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// Verify walks the hash chain from the first entry. If it is broken, it
+// returns the sequence number of the first entry that doesn't check out along
+// with ErrTampered. The chain must also end at the last entry the log knows
+// of, so that removing entries from the end is caught too.
+func (l *Log) Verify() (int, error) {
+	l.mux.Lock()
+	entries, err := l.read()
+	lastSeq, lastHash := l.lastSeq, l.lastHash
+	l.mux.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	prev := ""
+	for i, e := range entries {
+		if e.Seq != i+1 || e.PrevHash != prev || e.Hash != hash(e) {
+			return i + 1, ErrTampered
+		}
+		prev = e.Hash
+	}
+	if len(entries) < lastSeq || (len(entries) == lastSeq && prev != lastHash) {
+		return len(entries) + 1, ErrTampered
+	}
+	return 0, nil
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+// read parses every entry in the log file
+func (l *Log) read() ([]Entry, error) {
+	entries := []Entry{}
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		e := Entry{}
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			return nil, fmt.Errorf("audit log line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// hash returns the hex SHA-256 hash of the entry's contents, which include
+// the previous entry's hash
+func hash(e Entry) string {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,204 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// openTestLog opens a log in a temporary directory with the given number of
+// entries appended
+func openTestLog(t *testing.T, n int) (*Log, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	for i := 0; i < n; i++ {
+		_, err := l.Append(Entry{Event: "login", ActorID: i + 1, IP: "127.0.0.1", Target: "user:1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return l, path
+}
+
+// rewriteLines rewrites the log file with its lines changed by edit
+func rewriteLines(t *testing.T, path string, edit func([][]byte) [][]byte) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	lines = edit(lines)
+	out := append(bytes.Join(lines, []byte("\n")), '\n')
+	if len(lines) == 0 {
+		out = nil
+	}
+	err = os.WriteFile(path, out, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppendChainsEntries(t *testing.T) {
+	l, _ := openTestLog(t, 0)
+
+	first, err := l.Append(Entry{Event: "login"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := l.Append(Entry{Event: "logout"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Seq != 1 || first.PrevHash != "" || first.Hash != hash(first) {
+		t.Errorf("first entry = %+v, want seq 1, no previous hash and its own hash", first)
+	}
+	if second.Seq != 2 || second.PrevHash != first.Hash || second.Hash != hash(second) {
+		t.Errorf("second entry = %+v, want seq 2 chained to the first", second)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name         string
+		entries      int
+		edit         func([][]byte) [][]byte
+		wantBrokenAt int
+		wantErr      error
+	}{
+		{
+			name:    "empty chain",
+			entries: 0,
+		},
+		{
+			name:    "clean chain",
+			entries: 5,
+		},
+		{
+			name:    "modified entry",
+			entries: 5,
+			edit: func(lines [][]byte) [][]byte {
+				lines[2] = bytes.Replace(lines[2], []byte(`"actor_id":3`), []byte(`"actor_id":9`), 1)
+				return lines
+			},
+			wantBrokenAt: 3,
+			wantErr:      ErrTampered,
+		},
+		{
+			name:    "deleted entry",
+			entries: 5,
+			edit: func(lines [][]byte) [][]byte {
+				return append(lines[:1], lines[2:]...)
+			},
+			wantBrokenAt: 2,
+			wantErr:      ErrTampered,
+		},
+		{
+			name:    "deleted first entry",
+			entries: 5,
+			edit: func(lines [][]byte) [][]byte {
+				return lines[1:]
+			},
+			wantBrokenAt: 1,
+			wantErr:      ErrTampered,
+		},
+		{
+			name:    "deleted last entries",
+			entries: 5,
+			edit: func(lines [][]byte) [][]byte {
+				return lines[:3]
+			},
+			wantBrokenAt: 4,
+			wantErr:      ErrTampered,
+		},
+		{
+			name:    "reordered entries",
+			entries: 5,
+			edit: func(lines [][]byte) [][]byte {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			wantBrokenAt: 2,
+			wantErr:      ErrTampered,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, path := openTestLog(t, tt.entries)
+			if tt.edit != nil {
+				rewriteLines(t, path, tt.edit)
+			}
+
+			brokenAt, err := l.Verify()
+			if !errors.Is(err, tt.wantErr) || brokenAt != tt.wantBrokenAt {
+				t.Errorf("Verify() = %d, %v, want %d, %v", brokenAt, err, tt.wantBrokenAt, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReopenContinuesChain(t *testing.T) {
+	l, path := openTestLog(t, 3)
+	l.Close()
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	e, err := reopened.Append(Entry{Event: "login"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Seq != 4 {
+		t.Errorf("Seq after reopening = %d, want 4", e.Seq)
+	}
+	if brokenAt, err := reopened.Verify(); err != nil {
+		t.Errorf("Verify() after reopening = %d, %v, want a valid chain", brokenAt, err)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	l, _ := openTestLog(t, 5)
+	tests := []struct {
+		name    string
+		filter  Filter
+		wantIDs []int
+	}{
+		{"all, newest first", Filter{}, []int{5, 4, 3, 2, 1}},
+		{"limit", Filter{Limit: 2}, []int{5, 4}},
+		{"actor", Filter{ActorID: 3}, []int{3}},
+		{"event", Filter{Event: "logout"}, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := l.Query(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []int{}
+			for _, e := range entries {
+				got = append(got, e.ActorID)
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("Query(%+v) actors = %v, want %v", tt.filter, got, tt.wantIDs)
+			}
+			for i := range got {
+				if got[i] != tt.wantIDs[i] {
+					t.Errorf("Query(%+v) actors = %v, want %v", tt.filter, got, tt.wantIDs)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package db
+
+import (
+	"slices"
+	"time"
+)
+
+// DeletedUser holds the ID of a deleted account in the deleted_users
+// database table. Deleted IDs are never given to new accounts, so the old
+// account's tokens can't be used to act as someone else.
+type DeletedUser struct {
+	ID        int       `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// UserData holds everything stored about a user. Follows, follow requests,
+// blocks and mutes go both ways, and messages are those of the
+// conversations the user takes part in.
+type UserData struct {
+	User           User
+	Chirps         []Chirp
+	ChirpVersions  []ChirpVersion
+	Drafts         []Draft
+	Media          []Media
+	Likes          []Like
+	Bookmarks      []Bookmark
+	Votes          []Vote
+	Follows        []Follow
+	FollowRequests []FollowRequest
+	Blocks         []Block
+	Mutes          []Mute
+	Lists          []List
+	Notifications  []Notification
+	Conversations  []Conversation
+	Messages       []Message
+	Reports        []Report
+}
+
+// nextUserID returns the lowest ID that neither a user nor a deleted user has
+func nextUserID(dbStr dStruct) int {
+	id := 0
+	for {
+		id++
+		_, taken := dbStr.Users[id]
+		_, deleted := dbStr.DeletedUsers[id]
+		if !taken && !deleted {
+			return id
+		}
+	}
+}
+
+// GetUserData gathers everything stored about the user of the given ID
+func (db *DB) GetUserData(userID int) (UserData, error) {
+	data := UserData{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return data, err
+	}
+
+	u, ok := dbStr.Users[userID]
+	if !ok {
+		return data, ErrUserNotFound
+	}
+	data.User = u
+
+	chirpIDs := []int{}
+	data.Chirps = filterTable(dbStr.Chirps, func(c Chirp) bool { return c.AuthorID == userID })
+	for _, c := range data.Chirps {
+		chirpIDs = append(chirpIDs, c.ID)
+	}
+	data.ChirpVersions = filterTable(dbStr.ChirpVersions, func(v ChirpVersion) bool {
+		return slices.Contains(chirpIDs, v.ChirpID)
+	})
+	data.Drafts = filterTable(dbStr.Drafts, func(d Draft) bool { return d.AuthorID == userID })
+	data.Media = filterTable(dbStr.Media, func(m Media) bool { return m.OwnerID == userID })
+	data.Likes = filterTable(dbStr.Likes, func(l Like) bool { return l.UserID == userID })
+	data.Bookmarks = filterTable(dbStr.Bookmarks, func(b Bookmark) bool { return b.UserID == userID })
+	data.Votes = filterTable(dbStr.Votes, func(v Vote) bool { return v.UserID == userID })
+	data.Follows = filterTable(dbStr.Follows, func(f Follow) bool {
+		return f.FollowerID == userID || f.FolloweeID == userID
+	})
+	data.FollowRequests = filterTable(dbStr.FollowRequests, func(r FollowRequest) bool {
+		return r.FollowerID == userID || r.FolloweeID == userID
+	})
+	data.Blocks = filterTable(dbStr.Blocks, func(b Block) bool {
+		return b.BlockerID == userID || b.BlockedID == userID
+	})
+	data.Mutes = filterTable(dbStr.Mutes, func(m Mute) bool {
+		return m.MuterID == userID || m.MutedID == userID
+	})
+	data.Lists = filterTable(dbStr.Lists, func(l List) bool { return l.OwnerID == userID })
+	data.Notifications = filterTable(dbStr.Notifications, func(n Notification) bool { return n.UserID == userID })
+	data.Reports = filterTable(dbStr.Reports, func(r Report) bool { return r.ReporterID == userID })
+
+	convIDs := []int{}
+	data.Conversations = filterTable(dbStr.Conversations, func(c Conversation) bool {
+		return slices.Contains(c.ParticipantIDs, userID)
+	})
+	for _, c := range data.Conversations {
+		convIDs = append(convIDs, c.ID)
+	}
+	data.Messages = filterTable(dbStr.Messages, func(m Message) bool {
+		return slices.Contains(convIDs, m.ConversationID)
+	})
+
+	return data, nil
+}
+
+// DeleteUser deletes the user of the given ID along with their chirps,
+// drafts, media, likes (both theirs and those of their chirps), bookmarks,
+// votes, follows, follow requests, blocks, mutes, lists, notifications, spam
+// verdicts and the messages they sent. They are also taken out of other
+// users' lists and conversations. Reports and moderation actions are kept as
+// the moderation record. It returns the deleted chirps and media so their
+// blobs and in-memory state can be cleaned up.
+func (db *DB) DeleteUser(userID int) ([]Chirp, []Media, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, ok := dbStr.Users[userID]; !ok {
+		return nil, nil, ErrUserNotFound
+	}
+	delete(dbStr.Users, userID)
+	dbStr.DeletedUsers[userID] = DeletedUser{ID: userID, DeletedAt: time.Now()}
+
+	media := filterTable(dbStr.Media, func(m Media) bool { return m.OwnerID == userID })
+	chirps := []Chirp{}
+	for id, c := range dbStr.Chirps {
+		if c.AuthorID != userID {
+			continue
+		}
+		for _, other := range dbStr.Chirps {
+			if other.Kind == KindRechirp && other.OriginalID == id {
+				chirps = append(chirps, other)
+			}
+		}
+		chirps = append(chirps, c)
+		deleteChirp(dbStr, id)
+	}
+
+	deleteFrom(dbStr.Media, func(m Media) bool { return m.OwnerID == userID })
+	deleteFrom(dbStr.Drafts, func(d Draft) bool { return d.AuthorID == userID })
+	deleteFrom(dbStr.Likes, func(l Like) bool { return l.UserID == userID })
+	deleteFrom(dbStr.Bookmarks, func(b Bookmark) bool { return b.UserID == userID })
+	deleteFrom(dbStr.Votes, func(v Vote) bool { return v.UserID == userID })
+	deleteFrom(dbStr.Follows, func(f Follow) bool {
+		return f.FollowerID == userID || f.FolloweeID == userID
+	})
+	deleteFrom(dbStr.FollowRequests, func(r FollowRequest) bool {
+		return r.FollowerID == userID || r.FolloweeID == userID
+	})
+	deleteFrom(dbStr.Blocks, func(b Block) bool {
+		return b.BlockerID == userID || b.BlockedID == userID
+	})
+	deleteFrom(dbStr.Mutes, func(m Mute) bool {
+		return m.MuterID == userID || m.MutedID == userID
+	})
+	deleteFrom(dbStr.Lists, func(l List) bool { return l.OwnerID == userID })
+	deleteFrom(dbStr.Notifications, func(n Notification) bool {
+		return n.UserID == userID || n.ActorID == userID
+	})
+	deleteFrom(dbStr.SpamVerdicts, func(v SpamVerdict) bool { return v.AuthorID == userID })
+	deleteFrom(dbStr.Messages, func(m Message) bool { return m.SenderID == userID })
+
+	for id, l := range dbStr.Lists {
+		if slices.Contains(l.MemberIDs, userID) {
+			l.MemberIDs = slices.DeleteFunc(l.MemberIDs, func(memberID int) bool { return memberID == userID })
+			dbStr.Lists[id] = l
+		}
+	}
+	for id, c := range dbStr.Conversations {
+		if !slices.Contains(c.ParticipantIDs, userID) {
+			continue
+		}
+		c.ParticipantIDs = slices.DeleteFunc(c.ParticipantIDs, func(participantID int) bool { return participantID == userID })
+		delete(c.LastRead, userID)
+		if len(c.ParticipantIDs) == 0 {
+			delete(dbStr.Conversations, id)
+			continue
+		}
+		dbStr.Conversations[id] = c
+	}
+
+	return chirps, media, db.writeDB(dbStr)
+}
+
+// filterTable returns the rows of a table that match, ordered by ID
+func filterTable[T any](table map[int]T, match func(T) bool) []T {
+	ids := []int{}
+	for id, row := range table {
+		if match(row) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	rows := []T{}
+	for _, id := range ids {
+		rows = append(rows, table[id])
+	}
+	return rows
+}
+
+// deleteFrom deletes the rows of a table that match
+func deleteFrom[T any](table map[int]T, match func(T) bool) {
+	for id, row := range table {
+		if match(row) {
+			delete(table, id)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Block holds data associated with a block in the blocks database table
+type Block struct {
+	ID        int       `json:"id"`
+	BlockerID int       `json:"blocker_id"`
+	BlockedID int       `json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Mute holds data associated with a mute in the mutes database table
+type Mute struct {
+	ID        int       `json:"id"`
+	MuterID   int       `json:"muter_id"`
+	MutedID   int       `json:"muted_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateBlock makes the blocker block the blocked user, removing any follows
+// and follow requests between the two
+func (db *DB) CreateBlock(blockerID int, blockedID int) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	for _, b := range dbStr.Blocks {
+		if b.BlockerID == blockerID && b.BlockedID == blockedID {
+			return nil
+		}
+	}
+
+	for i, f := range dbStr.Follows {
+		if (f.FollowerID == blockerID && f.FolloweeID == blockedID) ||
+			(f.FollowerID == blockedID && f.FolloweeID == blockerID) {
+			delete(dbStr.Follows, i)
+		}
+	}
+	for i, r := range dbStr.FollowRequests {
+		if (r.FollowerID == blockerID && r.FolloweeID == blockedID) ||
+			(r.FollowerID == blockedID && r.FolloweeID == blockerID) {
+			delete(dbStr.FollowRequests, i)
+		}
+	}
+
+	newBlock := Block{
+		ID:        nextID(dbStr, "blocks", dbStr.Blocks),
+		BlockerID: blockerID,
+		BlockedID: blockedID,
+		CreatedAt: time.Now(),
+	}
+	dbStr.Blocks[newBlock.ID] = newBlock
+	return db.writeDB(dbStr)
+}
+
+// DeleteBlock makes the blocker unblock the blocked user
+func (db *DB) DeleteBlock(blockerID int, blockedID int) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	for i, b := range dbStr.Blocks {
+		if b.BlockerID == blockerID && b.BlockedID == blockedID {
+			delete(dbStr.Blocks, i)
+			return db.writeDB(dbStr)
+		}
+	}
+
+	return fmt.Errorf("block doesn't exist")
+}
+
+// GetBlocks returns all blocks in the database
+func (db *DB) GetBlocks() ([]Block, error) {
+	blocks := []Block{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return blocks, err
+	}
+
+	for _, b := range dbStr.Blocks {
+		blocks = append(blocks, b)
+	}
+
+	return blocks, err
+}
+
+// CreateMute makes the muter mute the muted user
+func (db *DB) CreateMute(muterID int, mutedID int) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range dbStr.Mutes {
+		if m.MuterID == muterID && m.MutedID == mutedID {
+			return nil
+		}
+	}
+
+	newMute := Mute{
+		ID:        nextID(dbStr, "mutes", dbStr.Mutes),
+		MuterID:   muterID,
+		MutedID:   mutedID,
+		CreatedAt: time.Now(),
+	}
+	dbStr.Mutes[newMute.ID] = newMute
+	return db.writeDB(dbStr)
+}
+
+// DeleteMute makes the muter unmute the muted user
+func (db *DB) DeleteMute(muterID int, mutedID int) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	for i, m := range dbStr.Mutes {
+		if m.MuterID == muterID && m.MutedID == mutedID {
+			delete(dbStr.Mutes, i)
+			return db.writeDB(dbStr)
+		}
+	}
+
+	return fmt.Errorf("mute doesn't exist")
+}
+
+// GetMutes returns all mutes in the database
+func (db *DB) GetMutes() ([]Mute, error) {
+	mutes := []Mute{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return mutes, err
+	}
+
+	for _, m := range dbStr.Mutes {
+		mutes = append(mutes, m)
+	}
+
+	return mutes, err
+}
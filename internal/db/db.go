@@ -3,6 +3,7 @@ package db
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -10,6 +11,9 @@ import (
 	"time"
 )
 
+// ErrUserNotFound is returned when there's no user with the given ID
+var ErrUserNotFound = errors.New("user doesn't exist")
+
 // DB is the database connection struct
 type DB struct {
 	path string
@@ -18,24 +22,136 @@ type DB struct {
 
 // dStruct is the struct representation of the database
 type dStruct struct {
-	Chirps        map[int]Chirp           `json:"chirps"`
-	Users         map[int]User            `json:"users"`
-	RevokedTokens map[string]RevokedToken `json:"revoked_tokens"`
+	Chirps         map[int]Chirp           `json:"chirps"`
+	Users          map[int]User            `json:"users"`
+	RevokedTokens  map[string]RevokedToken `json:"revoked_tokens"`
+	Follows        map[int]Follow          `json:"follows"`
+	Likes          map[int]Like            `json:"likes"`
+	Notifications  map[int]Notification    `json:"notifications"`
+	Conversations  map[int]Conversation    `json:"conversations"`
+	Messages       map[int]Message         `json:"messages"`
+	Media          map[int]Media           `json:"media"`
+	Blocks         map[int]Block           `json:"blocks"`
+	Mutes          map[int]Mute            `json:"mutes"`
+	FilterRules    map[string]FilterRule   `json:"filter_rules"`
+	ContentFlags   map[int]ContentFlag     `json:"content_flags"`
+	ChirpVersions  map[int]ChirpVersion    `json:"chirp_versions"`
+	Drafts         map[int]Draft           `json:"drafts"`
+	Polls          map[int]Poll            `json:"polls"`
+	Votes          map[int]Vote            `json:"votes"`
+	Bookmarks      map[int]Bookmark        `json:"bookmarks"`
+	Lists          map[int]List            `json:"lists"`
+	FollowRequests map[int]FollowRequest   `json:"follow_requests"`
+	Reports        map[int]Report          `json:"reports"`
+	ModActions     map[int]ModAction       `json:"moderation_actions"`
+	SpamVerdicts   map[int]SpamVerdict     `json:"spam_verdicts"`
+	DeletedUsers   map[int]DeletedUser     `json:"deleted_users"`
+	// Sequences holds the last ID given out for each table
+	Sequences map[string]int `json:"sequences"`
 }
 
+// Chirp visibilities. Public chirps can be seen by everyone, followers-only
+// chirps by the author's followers and mentioned-only chirps by the users
+// they mention. Users mentioned in a followers-only chirp can see it too, and
+// the author can always see their own chirps.
+const (
+	VisibilityPublic    = "public"
+	VisibilityFollowers = "followers"
+	VisibilityMentioned = "mentioned"
+)
+
+// Chirp kinds. A rechirp shares another chirp as-is and has no body of its
+// own, a quote shares another chirp with the author's commentary and a reply
+// answers another chirp. For all three, OriginalID is the other chirp's ID.
+const (
+	KindChirp   = "chirp"
+	KindRechirp = "rechirp"
+	KindQuote   = "quote"
+	KindReply   = "reply"
+)
+
 // Chirp holds data associated with a chirp in the chirps database table
 type Chirp struct {
-	ID       int    `json:"id"`
-	AuthorID int    `json:"author_id"`
-	Body     string `json:"body"`
+	ID         int       `json:"id"`
+	AuthorID   int       `json:"author_id"`
+	Body       string    `json:"body"`
+	Kind       string    `json:"kind"`
+	OriginalID int       `json:"original_id,omitempty"`
+	Mentions   []Mention `json:"mentions"`
+	Hashtags   []Hashtag `json:"hashtags"`
+	MediaIDs   []int     `json:"media_ids,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Edited is set once the body has been changed after posting, with
+	// EditedAt being the time of the latest edit
+	Edited   bool       `json:"edited"`
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+	// Visibility is one of the Visibility constants. Chirps saved before
+	// visibilities existed have none and are public.
+	Visibility string `json:"visibility"`
+	// Held chirps scored as likely spam and are only visible to their author
+	// until a moderator reviews them
+	Held bool `json:"held,omitempty"`
+}
+
+// Mention is a reference to a user in a chirp body. Start and End are offsets
+// into the body in grapheme clusters (user-perceived characters, the unit
+// chirp length is counted in), with End being exclusive.
+type Mention struct {
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Text   string `json:"text"`
+	UserID int    `json:"user_id"`
 }
 
+// Hashtag is a tag in a chirp body. Start and End are offsets into the body in
+// grapheme clusters, with End being exclusive. Tag is lowercased and without
+// the leading "#".
+type Hashtag struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Tag   string `json:"tag"`
+}
+
+// User roles. Moderators handle reports and can delete any chirp, and admins
+// can additionally manage the server and other users' roles.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 // User holds data associated with a user in the users database table
 type User struct {
 	ID          int    `json:"id"`
 	Email       string `json:"email"`
 	Password    string `json:"user"`
 	IsChirpyRed bool   `json:"is_chirpy_red"`
+	Handle      string `json:"handle"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	AvatarURL   string `json:"avatar_url"`
+	// MutedNotifications lists the notification types the user doesn't want
+	// to receive
+	MutedNotifications []string `json:"muted_notifications"`
+	// OpenDMs allows users the user doesn't follow to message them
+	OpenDMs bool `json:"open_dms"`
+	// Protected accounts approve their followers, and their public chirps
+	// are only visible to them
+	Protected bool `json:"protected"`
+	// Suspended users can't log in. A suspension without SuspendedUntil
+	// lasts until it's lifted.
+	Suspended        bool       `json:"suspended"`
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+	// ShadowBanned users' chirps are only visible to themselves
+	ShadowBanned bool `json:"shadow_banned"`
+	// Role is one of the Role constants. Users saved before roles existed
+	// have none and are regular users.
+	Role string `json:"role"`
+	// CreatedAt is zero for users saved before it was recorded
+	CreatedAt time.Time `json:"created_at"`
+	// Tokens issued before SessionsRevokedAt are no longer accepted
+	SessionsRevokedAt *time.Time `json:"sessions_revoked_at,omitempty"`
 }
 
 // RevokedToken holds data associated with a revoked token in the
@@ -54,31 +170,36 @@ func NewDB(path string) (*DB, error) {
 	return newDB, err
 }
 
-// CreateChirp creates a new chirp and saves it to disk
-func (db *DB) CreateChirp(authorID int, body string) (Chirp, error) {
+// CreateChirp saves the given chirp to disk under a newly assigned ID, and
+// attaches its media to it. A chirp without a kind is saved as a regular
+// chirp, and one without a visibility as a public chirp.
+func (db *DB) CreateChirp(newChirp Chirp) (Chirp, error) {
 	fmt.Println("Creating chirp...")
 	db.mux.Lock()
 	defer db.mux.Unlock()
 
-	newChirp := Chirp{}
-
 	dbStr, err := db.loadDB()
 	if err != nil {
 		fmt.Println("Err loading DB...", err)
 		return newChirp, err
 	}
 
-	id := 0
-	for {
-		id++
-		if _, ok := dbStr.Chirps[id]; !ok {
-			break
-		}
+	id := nextID(dbStr, "chirps", dbStr.Chirps)
+	newChirp.ID = id
+	if newChirp.Kind == "" {
+		newChirp.Kind = KindChirp
+	}
+	if newChirp.Visibility == "" {
+		newChirp.Visibility = VisibilityPublic
+	}
+	if newChirp.CreatedAt.IsZero() {
+		newChirp.CreatedAt = time.Now()
 	}
 
-	newChirp.ID = id
-	newChirp.AuthorID = authorID
-	newChirp.Body = body
+	err = attachMedia(dbStr, newChirp)
+	if err != nil {
+		return newChirp, err
+	}
 
 	dbStr.Chirps[id] = newChirp
 	err = db.writeDB(dbStr)
@@ -90,8 +211,9 @@ func (db *DB) CreateChirp(authorID int, body string) (Chirp, error) {
 	return newChirp, nil
 }
 
-// CreateUser creates a new user and saves it to disk
-func (db *DB) CreateUser(email string, hPassword string) (User, error) {
+// CreateUser creates a new user and saves it to disk. The handle must not be
+// taken by another user.
+func (db *DB) CreateUser(email string, hPassword string, handle string) (User, error) {
 	fmt.Println("Creating user...")
 	db.mux.Lock()
 	defer db.mux.Unlock()
@@ -104,17 +226,20 @@ func (db *DB) CreateUser(email string, hPassword string) (User, error) {
 		return newUser, err
 	}
 
-	id := 0
-	for {
-		id++
-		if _, ok := dbStr.Users[id]; !ok {
-			break
-		}
+	if emailTaken(dbStr, email, 0) {
+		return newUser, ErrEmailTaken
+	}
+	if handleTaken(dbStr, handle, 0) {
+		return newUser, ErrHandleTaken
 	}
 
+	id := nextUserID(dbStr)
 	newUser.ID = id
 	newUser.Email = email
 	newUser.Password = hPassword
+	newUser.Handle = handle
+	newUser.Role = RoleUser
+	newUser.CreatedAt = time.Now()
 
 	dbStr.Users[id] = newUser
 	err = db.writeDB(dbStr)
@@ -142,30 +267,53 @@ func (db *DB) GetUsers() ([]User, error) {
 	return users, err
 }
 
-// UpdateUser updates the data for the given ID with a new email and (hashed)
-// password
+// GetUser returns the user of the given ID
+func (db *DB) GetUser(id int) (User, error) {
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return User{}, err
+	}
+
+	u, ok := dbStr.Users[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return u, nil
+}
+
+// UpdateUser updates the user of the given ID with a new email and (hashed)
+// password, leaving either unchanged if it's empty. Changing the password
+// revokes the user's sessions, so tokens issued before the change stop
+// working.
 func (db *DB) UpdateUser(id int, newEmail string, hNewPassword string) (User, error) {
-	newUser := User{}
+	db.mux.Lock()
+	defer db.mux.Unlock()
 
-	dbStruct, err := db.loadDB()
+	dbStr, err := db.loadDB()
 	if err != nil {
-		return newUser, err
+		return User{}, err
 	}
 
-	for i, u := range dbStruct.Users {
-		if u.ID == id {
-			newUser = User{
-				ID:       id,
-				Email:    newEmail,
-				Password: hNewPassword,
-			}
-			dbStruct.Users[i] = newUser
-			break
+	u, ok := dbStr.Users[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	if newEmail != "" {
+		if emailTaken(dbStr, newEmail, id) {
+			return User{}, ErrEmailTaken
 		}
+		u.Email = newEmail
+	}
+	if hNewPassword != "" {
+		// Token issue times only have second precision
+		revokedAt := time.Now().Truncate(time.Second)
+		u.Password = hNewPassword
+		u.SessionsRevokedAt = &revokedAt
 	}
 
-	err = db.writeDB(dbStruct)
-	return newUser, err
+	dbStr.Users[id] = u
+	return u, db.writeDB(dbStr)
 }
 
 // GetChirps returns all chirps in the database
@@ -184,20 +332,44 @@ func (db *DB) GetChirps() ([]Chirp, error) {
 	return chirps, err
 }
 
-// ensureDB creates a new database file if it doesn't exist
+// ensureDB creates a new database file if it doesn't exist. An existing file
+// is kept so that data (such as scheduled chirps) survives restarts.
 func (db *DB) ensureDB() error {
-	if _, err := os.Stat(db.path); os.IsNotExist(err) {
-		_, err := os.Create(db.path)
-		if err != nil {
-			return err
-		}
+	info, err := os.Stat(db.path)
+	if err == nil && info.Size() > 0 {
+		return nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
 	}
 
 	emptyDB, err := json.Marshal(
 		dStruct{
-			Chirps:        map[int]Chirp{},
-			Users:         map[int]User{},
-			RevokedTokens: map[string]RevokedToken{},
+			Chirps:         map[int]Chirp{},
+			Users:          map[int]User{},
+			RevokedTokens:  map[string]RevokedToken{},
+			Follows:        map[int]Follow{},
+			Likes:          map[int]Like{},
+			Notifications:  map[int]Notification{},
+			Conversations:  map[int]Conversation{},
+			Messages:       map[int]Message{},
+			Media:          map[int]Media{},
+			Blocks:         map[int]Block{},
+			Mutes:          map[int]Mute{},
+			FilterRules:    map[string]FilterRule{},
+			ContentFlags:   map[int]ContentFlag{},
+			ChirpVersions:  map[int]ChirpVersion{},
+			Drafts:         map[int]Draft{},
+			Polls:          map[int]Poll{},
+			Votes:          map[int]Vote{},
+			Bookmarks:      map[int]Bookmark{},
+			Lists:          map[int]List{},
+			FollowRequests: map[int]FollowRequest{},
+			Reports:        map[int]Report{},
+			ModActions:     map[int]ModAction{},
+			SpamVerdicts:   map[int]SpamVerdict{},
+			DeletedUsers:   map[int]DeletedUser{},
+			Sequences:      map[string]int{},
 		},
 	)
 	if err != nil {
@@ -217,10 +389,97 @@ func (db *DB) loadDB() (dStruct, error) {
 	}
 
 	err = json.Unmarshal(dbBytes, &dbStr)
-	return dbStr, err
+	if err != nil {
+		return dbStr, err
+	}
+
+	// Tables added after the database file was created are missing from it
+	if dbStr.Follows == nil {
+		dbStr.Follows = map[int]Follow{}
+	}
+	if dbStr.Likes == nil {
+		dbStr.Likes = map[int]Like{}
+	}
+	if dbStr.Notifications == nil {
+		dbStr.Notifications = map[int]Notification{}
+	}
+	if dbStr.Conversations == nil {
+		dbStr.Conversations = map[int]Conversation{}
+	}
+	if dbStr.Messages == nil {
+		dbStr.Messages = map[int]Message{}
+	}
+	if dbStr.Media == nil {
+		dbStr.Media = map[int]Media{}
+	}
+	if dbStr.Blocks == nil {
+		dbStr.Blocks = map[int]Block{}
+	}
+	if dbStr.Mutes == nil {
+		dbStr.Mutes = map[int]Mute{}
+	}
+	if dbStr.FilterRules == nil {
+		dbStr.FilterRules = map[string]FilterRule{}
+	}
+	if dbStr.ContentFlags == nil {
+		dbStr.ContentFlags = map[int]ContentFlag{}
+	}
+	if dbStr.ChirpVersions == nil {
+		dbStr.ChirpVersions = map[int]ChirpVersion{}
+	}
+	if dbStr.Drafts == nil {
+		dbStr.Drafts = map[int]Draft{}
+	}
+	if dbStr.Polls == nil {
+		dbStr.Polls = map[int]Poll{}
+	}
+	if dbStr.Votes == nil {
+		dbStr.Votes = map[int]Vote{}
+	}
+	if dbStr.Bookmarks == nil {
+		dbStr.Bookmarks = map[int]Bookmark{}
+	}
+	if dbStr.Lists == nil {
+		dbStr.Lists = map[int]List{}
+	}
+	if dbStr.FollowRequests == nil {
+		dbStr.FollowRequests = map[int]FollowRequest{}
+	}
+	if dbStr.Reports == nil {
+		dbStr.Reports = map[int]Report{}
+	}
+	if dbStr.ModActions == nil {
+		dbStr.ModActions = map[int]ModAction{}
+	}
+	if dbStr.SpamVerdicts == nil {
+		dbStr.SpamVerdicts = map[int]SpamVerdict{}
+	}
+	if dbStr.DeletedUsers == nil {
+		dbStr.DeletedUsers = map[int]DeletedUser{}
+	}
+	if dbStr.Sequences == nil {
+		dbStr.Sequences = map[string]int{}
+	}
+	return dbStr, nil
+}
+
+// nextID returns a new ID for the named table. IDs are never reused, so
+// records still referring to a deleted row can't end up pointing at a new
+// one. The sequence starts from the table's highest ID for database files
+// written before sequences were kept.
+func nextID[T any](dbStr dStruct, name string, table map[int]T) int {
+	id := dbStr.Sequences[name]
+	for existing := range table {
+		id = max(id, existing)
+	}
+	id++
+	dbStr.Sequences[name] = id
+	return id
 }
 
-// writeDB writes the database file to disk
+// writeDB writes the database file to disk. The file is written under a
+// temporary name and then renamed over the old one, so that reads (which don't
+// take the lock) never see a partially written file.
 func (db *DB) writeDB(dbStr dStruct) error {
 	fmt.Println("Saving to disk...")
 	dbBytes, err := json.Marshal(dbStr)
@@ -228,12 +487,13 @@ func (db *DB) writeDB(dbStr dStruct) error {
 		return err
 	}
 
-	err = os.WriteFile(db.path, dbBytes, 0644)
+	tmpPath := db.path + ".tmp"
+	err = os.WriteFile(tmpPath, dbBytes, 0644)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return os.Rename(tmpPath, db.path)
 }
 
 // AddRevokedToken adds the given token to the revoked tokens database table to
@@ -279,7 +539,9 @@ func (db *DB) GetRevokedTokens() ([]RevokedToken, error) {
 	return tokens, err
 }
 
-// DeleteChirp deletes the chirp of the given ID
+// DeleteChirp deletes the chirp of the given ID along with its media records,
+// content flags, previous versions, poll, bookmarks, likes, notifications and
+// any rechirps of it. Quotes are kept since they carry their own commentary.
 func (db *DB) DeleteChirp(chirpID string) error {
 	db.mux.Lock()
 	defer db.mux.Unlock()
@@ -294,20 +556,73 @@ func (db *DB) DeleteChirp(chirpID string) error {
 		return err
 	}
 
-	for _, c := range dbStr.Chirps {
-		if c.ID == id {
-			delete(dbStr.Chirps, id)
-			err := db.writeDB(dbStr)
-			return err
-		}
+	if _, ok := dbStr.Chirps[id]; !ok {
+		return fmt.Errorf("chirp doesn't exist")
 	}
 
-	return fmt.Errorf("chirp doesn't exist")
+	deleteChirp(dbStr, id)
+	return db.writeDB(dbStr)
+}
+
+// deleteChirp deletes the chirp of the given ID from the tables along with
+// its media, rechirps, content flags, versions, poll, bookmarks, likes and
+// notifications. Moderator notifications about the chirp are kept.
+func deleteChirp(dbStr dStruct, id int) {
+	delete(dbStr.Chirps, id)
+	for i, m := range dbStr.Media {
+		if m.ChirpID == id {
+			delete(dbStr.Media, i)
+		}
+	}
+	for i, c := range dbStr.Chirps {
+		if c.Kind == KindRechirp && c.OriginalID == id {
+			deleteChirp(dbStr, i)
+		}
+	}
+	for i, l := range dbStr.Likes {
+		if l.ChirpID == id {
+			delete(dbStr.Likes, i)
+		}
+	}
+	for i, n := range dbStr.Notifications {
+		if n.ChirpID == id && n.Type != NotifyWarning && n.Type != NotifyChirpRemoved {
+			delete(dbStr.Notifications, i)
+		}
+	}
+	for i, f := range dbStr.ContentFlags {
+		if f.ChirpID == id {
+			delete(dbStr.ContentFlags, i)
+		}
+	}
+	for i, v := range dbStr.ChirpVersions {
+		if v.ChirpID == id {
+			delete(dbStr.ChirpVersions, i)
+		}
+	}
+	for i, p := range dbStr.Polls {
+		if p.ChirpID != id {
+			continue
+		}
+		for j, v := range dbStr.Votes {
+			if v.PollID == p.ID {
+				delete(dbStr.Votes, j)
+			}
+		}
+		delete(dbStr.Polls, i)
+	}
+	for i, b := range dbStr.Bookmarks {
+		if b.ChirpID == id {
+			delete(dbStr.Bookmarks, i)
+		}
+	}
 }
 
 // UpgradeChirpyRed upgrades the user with the given ID for Chirpy Red
 // subscription
 func (db *DB) UpgradeChirpyRed(userID int) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
 	dbStruct, err := db.loadDB()
 	if err != nil {
 		return err
@@ -315,13 +630,8 @@ func (db *DB) UpgradeChirpyRed(userID int) error {
 
 	for i, u := range dbStruct.Users {
 		if u.ID == userID {
-			newUser := User{
-				ID:          u.ID,
-				Email:       u.Email,
-				Password:    u.Password,
-				IsChirpyRed: true,
-			}
-			dbStruct.Users[i] = newUser
+			u.IsChirpyRed = true
+			dbStruct.Users[i] = u
 			break
 		}
 	}
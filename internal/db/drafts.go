@@ -0,0 +1,168 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrDraftChanged is returned when publishing a draft that was edited,
+// deleted or already published since it was read
+var ErrDraftChanged = errors.New("draft changed")
+
+// Draft statuses. A draft is kept until the user publishes or schedules it,
+// a scheduled draft is published by the scheduler at PublishAt, and a failed
+// draft couldn't be published when it was due (see Error).
+const (
+	DraftSaved     = "draft"
+	DraftScheduled = "scheduled"
+	DraftPublished = "published"
+	DraftFailed    = "failed"
+)
+
+// Draft holds data associated with an unpublished or scheduled chirp in the
+// drafts database table. ChirpID is set once the draft has been published.
+type Draft struct {
+	ID         int        `json:"id"`
+	AuthorID   int        `json:"author_id"`
+	Body       string     `json:"body"`
+	MediaIDs   []int      `json:"media_ids,omitempty"`
+	Visibility string     `json:"visibility"`
+	Status     string     `json:"status"`
+	PublishAt  *time.Time `json:"publish_at,omitempty"`
+	ChirpID    int        `json:"chirp_id,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// SaveDraft creates the given draft, or replaces it if it has an ID
+func (db *DB) SaveDraft(d Draft) (Draft, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return d, err
+	}
+
+	now := time.Now()
+	if d.ID == 0 {
+		d.ID = nextID(dbStr, "drafts", dbStr.Drafts)
+		d.CreatedAt = now
+	} else {
+		old, ok := dbStr.Drafts[d.ID]
+		if !ok {
+			return d, fmt.Errorf("draft doesn't exist")
+		}
+		d.CreatedAt = old.CreatedAt
+	}
+	d.UpdatedAt = now
+
+	dbStr.Drafts[d.ID] = d
+	return d, db.writeDB(dbStr)
+}
+
+// GetDrafts returns all drafts in the database
+func (db *DB) GetDrafts() ([]Draft, error) {
+	drafts := []Draft{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return drafts, err
+	}
+
+	for _, d := range dbStr.Drafts {
+		drafts = append(drafts, d)
+	}
+
+	return drafts, err
+}
+
+// DeleteDraft deletes the draft of the given ID
+func (db *DB) DeleteDraft(id int) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := dbStr.Drafts[id]; !ok {
+		return fmt.Errorf("draft doesn't exist")
+	}
+
+	delete(dbStr.Drafts, id)
+	return db.writeDB(dbStr)
+}
+
+// PublishDraft saves the given chirp as the publication of the given draft,
+// marking the draft as published. Both happen in a single write, and only if
+// the draft hasn't changed since it was read, so a draft can never be
+// published twice.
+func (db *DB) PublishDraft(d Draft, newChirp Chirp) (Chirp, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return newChirp, err
+	}
+
+	if !unchanged(dbStr, d) {
+		return newChirp, ErrDraftChanged
+	}
+
+	newChirp.ID = nextID(dbStr, "chirps", dbStr.Chirps)
+	if newChirp.Kind == "" {
+		newChirp.Kind = KindChirp
+	}
+	if newChirp.Visibility == "" {
+		newChirp.Visibility = VisibilityPublic
+	}
+	newChirp.CreatedAt = time.Now()
+
+	err = attachMedia(dbStr, newChirp)
+	if err != nil {
+		return newChirp, err
+	}
+	dbStr.Chirps[newChirp.ID] = newChirp
+
+	d.Status = DraftPublished
+	d.ChirpID = newChirp.ID
+	d.Error = ""
+	d.UpdatedAt = newChirp.CreatedAt
+	dbStr.Drafts[d.ID] = d
+
+	return newChirp, db.writeDB(dbStr)
+}
+
+// FailDraft marks the given draft as failed with the given reason, unless it
+// has changed since it was read
+func (db *DB) FailDraft(d Draft, reason string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	if !unchanged(dbStr, d) {
+		return ErrDraftChanged
+	}
+
+	d.Status = DraftFailed
+	d.Error = reason
+	d.UpdatedAt = time.Now()
+	dbStr.Drafts[d.ID] = d
+	return db.writeDB(dbStr)
+}
+
+// unchanged reports whether the stored draft is still the given one and
+// hasn't been published
+func unchanged(dbStr dStruct, d Draft) bool {
+	stored, ok := dbStr.Drafts[d.ID]
+	return ok && stored.Status != DraftPublished && stored.UpdatedAt.Equal(d.UpdatedAt)
+}
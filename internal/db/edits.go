@@ -0,0 +1,88 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// ChirpVersion holds a previous version of an edited chirp in the
+// chirp_versions database table. Version numbers start at 1 for the chirp as
+// originally posted, and CreatedAt is when that version was written.
+type ChirpVersion struct {
+	ID        int       `json:"id"`
+	ChirpID   int       `json:"chirp_id"`
+	Version   int       `json:"version"`
+	Body      string    `json:"body"`
+	Mentions  []Mention `json:"mentions"`
+	Hashtags  []Hashtag `json:"hashtags"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EditChirp replaces the body, mentions and hashtags of the given chirp,
+// keeping the current ones as a previous version, and holds it for review if
+// the edited chirp is held
+func (db *DB) EditChirp(edited Chirp) (Chirp, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return edited, err
+	}
+
+	c, ok := dbStr.Chirps[edited.ID]
+	if !ok {
+		return edited, fmt.Errorf("chirp doesn't exist")
+	}
+
+	version := 1
+	writtenAt := c.CreatedAt
+	for _, v := range dbStr.ChirpVersions {
+		if v.ChirpID == c.ID {
+			version++
+		}
+	}
+	if c.EditedAt != nil {
+		writtenAt = *c.EditedAt
+	}
+
+	prev := ChirpVersion{
+		ID:        nextID(dbStr, "chirp_versions", dbStr.ChirpVersions),
+		ChirpID:   c.ID,
+		Version:   version,
+		Body:      c.Body,
+		Mentions:  c.Mentions,
+		Hashtags:  c.Hashtags,
+		CreatedAt: writtenAt,
+	}
+	dbStr.ChirpVersions[prev.ID] = prev
+
+	now := time.Now()
+	c.Body = edited.Body
+	c.Mentions = edited.Mentions
+	c.Hashtags = edited.Hashtags
+	c.Edited = true
+	c.EditedAt = &now
+	c.Held = edited.Held
+	dbStr.Chirps[c.ID] = c
+
+	return c, db.writeDB(dbStr)
+}
+
+// GetChirpVersions returns the previous versions of the chirp of the given ID
+func (db *DB) GetChirpVersions(chirpID int) ([]ChirpVersion, error) {
+	versions := []ChirpVersion{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return versions, err
+	}
+
+	for _, v := range dbStr.ChirpVersions {
+		if v.ChirpID == chirpID {
+			versions = append(versions, v)
+		}
+	}
+
+	return versions, err
+}
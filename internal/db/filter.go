@@ -0,0 +1,112 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// FilterRule holds data associated with a banned word in the filter_rules
+// database table
+type FilterRule struct {
+	Word   string `json:"word"`
+	Action string `json:"action"`
+}
+
+// ContentFlag holds data associated with a chirp held for review in the
+// content_flags database table. Words are the banned words the chirp
+// contains.
+type ContentFlag struct {
+	ID        int       `json:"id"`
+	ChirpID   int       `json:"chirp_id"`
+	AuthorID  int       `json:"author_id"`
+	Words     []string  `json:"words"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SetFilterRule adds or replaces the rule for the given word
+func (db *DB) SetFilterRule(rule FilterRule) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	dbStr.FilterRules[rule.Word] = rule
+	return db.writeDB(dbStr)
+}
+
+// DeleteFilterRule deletes the rule for the given word
+func (db *DB) DeleteFilterRule(word string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := dbStr.FilterRules[word]; !ok {
+		return fmt.Errorf("filter rule doesn't exist")
+	}
+
+	delete(dbStr.FilterRules, word)
+	return db.writeDB(dbStr)
+}
+
+// GetFilterRules returns all filter rules in the database
+func (db *DB) GetFilterRules() ([]FilterRule, error) {
+	rules := []FilterRule{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return rules, err
+	}
+
+	for _, r := range dbStr.FilterRules {
+		rules = append(rules, r)
+	}
+
+	return rules, err
+}
+
+// CreateContentFlag records that the chirp of the given ID contains words
+// flagged for review
+func (db *DB) CreateContentFlag(chirpID int, authorID int, words []string) (ContentFlag, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	newFlag := ContentFlag{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return newFlag, err
+	}
+
+	newFlag = ContentFlag{
+		ID:        nextID(dbStr, "content_flags", dbStr.ContentFlags),
+		ChirpID:   chirpID,
+		AuthorID:  authorID,
+		Words:     words,
+		CreatedAt: time.Now(),
+	}
+	dbStr.ContentFlags[newFlag.ID] = newFlag
+	return newFlag, db.writeDB(dbStr)
+}
+
+// GetContentFlags returns all content flags in the database
+func (db *DB) GetContentFlags() ([]ContentFlag, error) {
+	flags := []ContentFlag{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return flags, err
+	}
+
+	for _, f := range dbStr.ContentFlags {
+		flags = append(flags, f)
+	}
+
+	return flags, err
+}
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Bookmark holds data associated with a bookmark in the bookmarks database
+// table
+type Bookmark struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	ChirpID   int       `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// List holds data associated with a curated list of accounts in the lists
+// database table
+type List struct {
+	ID          int       `json:"id"`
+	OwnerID     int       `json:"owner_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Private     bool      `json:"private"`
+	MemberIDs   []int     `json:"member_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateBookmark bookmarks the chirp for the user. Bookmarking a chirp twice
+// has no effect.
+func (db *DB) CreateBookmark(userID int, chirpID int) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	for _, b := range dbStr.Bookmarks {
+		if b.UserID == userID && b.ChirpID == chirpID {
+			return nil
+		}
+	}
+
+	newBookmark := Bookmark{
+		ID:        nextID(dbStr, "bookmarks", dbStr.Bookmarks),
+		UserID:    userID,
+		ChirpID:   chirpID,
+		CreatedAt: time.Now(),
+	}
+	dbStr.Bookmarks[newBookmark.ID] = newBookmark
+	return db.writeDB(dbStr)
+}
+
+// DeleteBookmark removes the user's bookmark of the chirp
+func (db *DB) DeleteBookmark(userID int, chirpID int) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	for i, b := range dbStr.Bookmarks {
+		if b.UserID == userID && b.ChirpID == chirpID {
+			delete(dbStr.Bookmarks, i)
+			return db.writeDB(dbStr)
+		}
+	}
+
+	return fmt.Errorf("bookmark doesn't exist")
+}
+
+// GetBookmarks returns all bookmarks in the database
+func (db *DB) GetBookmarks() ([]Bookmark, error) {
+	bookmarks := []Bookmark{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return bookmarks, err
+	}
+
+	for _, b := range dbStr.Bookmarks {
+		bookmarks = append(bookmarks, b)
+	}
+
+	return bookmarks, err
+}
+
+// SaveList creates the given list, or replaces its name, description and
+// privacy if it has an ID. Members are managed separately.
+func (db *DB) SaveList(l List) (List, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return l, err
+	}
+
+	if l.ID == 0 {
+		l.ID = nextID(dbStr, "lists", dbStr.Lists)
+		l.MemberIDs = []int{}
+		l.CreatedAt = time.Now()
+	} else {
+		old, ok := dbStr.Lists[l.ID]
+		if !ok {
+			return l, fmt.Errorf("list doesn't exist")
+		}
+		old.Name = l.Name
+		old.Description = l.Description
+		old.Private = l.Private
+		l = old
+	}
+
+	dbStr.Lists[l.ID] = l
+	return l, db.writeDB(dbStr)
+}
+
+// DeleteList deletes the list of the given ID
+func (db *DB) DeleteList(id int) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := dbStr.Lists[id]; !ok {
+		return fmt.Errorf("list doesn't exist")
+	}
+
+	delete(dbStr.Lists, id)
+	return db.writeDB(dbStr)
+}
+
+// GetLists returns all lists in the database
+func (db *DB) GetLists() ([]List, error) {
+	lists := []List{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return lists, err
+	}
+
+	for _, l := range dbStr.Lists {
+		lists = append(lists, l)
+	}
+
+	return lists, err
+}
+
+// AddListMember adds the user to the list of the given ID. Adding a member
+// twice has no effect.
+func (db *DB) AddListMember(listID int, userID int) (List, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return List{}, err
+	}
+
+	l, ok := dbStr.Lists[listID]
+	if !ok {
+		return l, fmt.Errorf("list doesn't exist")
+	}
+	if slices.Contains(l.MemberIDs, userID) {
+		return l, nil
+	}
+
+	l.MemberIDs = append(l.MemberIDs, userID)
+	dbStr.Lists[listID] = l
+	return l, db.writeDB(dbStr)
+}
+
+// RemoveListMember removes the user from the list of the given ID
+func (db *DB) RemoveListMember(listID int, userID int) (List, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return List{}, err
+	}
+
+	l, ok := dbStr.Lists[listID]
+	if !ok || !slices.Contains(l.MemberIDs, userID) {
+		return l, fmt.Errorf("list member doesn't exist")
+	}
+
+	l.MemberIDs = slices.DeleteFunc(l.MemberIDs, func(id int) bool { return id == userID })
+	dbStr.Lists[listID] = l
+	return l, db.writeDB(dbStr)
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidMedia is returned when a chirp refers to media that doesn't
+// exist, belongs to another user or is already attached to another chirp
+var ErrInvalidMedia = errors.New("invalid media")
+
+// Media holds data associated with an uploaded image in the media database
+// table. Key and ThumbnailKey are the blob store keys of the image and its
+// thumbnail. ChirpID is zero until the media is attached to a chirp.
+type Media struct {
+	ID           int       `json:"id"`
+	OwnerID      int       `json:"owner_id"`
+	ChirpID      int       `json:"chirp_id"`
+	Key          string    `json:"key"`
+	ThumbnailKey string    `json:"thumbnail_key"`
+	ContentType  string    `json:"content_type"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// CreateMedia saves a new media record to disk under a newly assigned ID
+func (db *DB) CreateMedia(m Media) (Media, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return m, err
+	}
+
+	m.ID = nextID(dbStr, "media", dbStr.Media)
+	m.CreatedAt = time.Now()
+	dbStr.Media[m.ID] = m
+	return m, db.writeDB(dbStr)
+}
+
+// GetMedia returns all media in the database
+func (db *DB) GetMedia() ([]Media, error) {
+	media := []Media{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return media, err
+	}
+
+	for _, m := range dbStr.Media {
+		media = append(media, m)
+	}
+
+	return media, err
+}
+
+// attachMedia attaches the given media to a chirp. The caller must hold the
+// lock and write the database afterwards.
+func attachMedia(dbStr dStruct, c Chirp) error {
+	for _, id := range c.MediaIDs {
+		m, ok := dbStr.Media[id]
+		if !ok || m.OwnerID != c.AuthorID || m.ChirpID != 0 {
+			return ErrInvalidMedia
+		}
+	}
+
+	for _, id := range c.MediaIDs {
+		m := dbStr.Media[id]
+		m.ChirpID = c.ID
+		dbStr.Media[id] = m
+	}
+	return nil
+}
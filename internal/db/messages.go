@@ -0,0 +1,165 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Conversation holds data associated with a conversation in the conversations
+// database table. LastRead maps each participant to the ID of the last message
+// they've read, which serves as a read receipt.
+type Conversation struct {
+	ID             int         `json:"id"`
+	ParticipantIDs []int       `json:"participant_ids"`
+	LastRead       map[int]int `json:"last_read"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+// Message holds data associated with a direct message in the messages
+// database table
+type Message struct {
+	ID             int       `json:"id"`
+	ConversationID int       `json:"conversation_id"`
+	SenderID       int       `json:"sender_id"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CreateConversation saves a new conversation between the given users to disk
+func (db *DB) CreateConversation(participantIDs []int) (Conversation, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return Conversation{}, err
+	}
+
+	newConv := Conversation{
+		ID:             nextID(dbStr, "conversations", dbStr.Conversations),
+		ParticipantIDs: participantIDs,
+		LastRead:       map[int]int{},
+		CreatedAt:      time.Now(),
+	}
+	dbStr.Conversations[newConv.ID] = newConv
+	err = db.writeDB(dbStr)
+	return newConv, err
+}
+
+// GetConversations returns all conversations in the database
+func (db *DB) GetConversations() ([]Conversation, error) {
+	convs := []Conversation{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return convs, err
+	}
+
+	for _, c := range dbStr.Conversations {
+		convs = append(convs, c)
+	}
+
+	return convs, err
+}
+
+// CreateMessage saves a new message in the given conversation to disk. The
+// sender is considered to have read the conversation up to their message.
+func (db *DB) CreateMessage(conversationID int, senderID int, body string) (Message, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return Message{}, err
+	}
+
+	conv, ok := dbStr.Conversations[conversationID]
+	if !ok {
+		return Message{}, fmt.Errorf("conversation doesn't exist")
+	}
+
+	newMsg := Message{
+		ID:             nextID(dbStr, "messages", dbStr.Messages),
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Body:           body,
+		CreatedAt:      time.Now(),
+	}
+	dbStr.Messages[newMsg.ID] = newMsg
+
+	if conv.LastRead == nil {
+		conv.LastRead = map[int]int{}
+	}
+	conv.LastRead[senderID] = newMsg.ID
+	dbStr.Conversations[conversationID] = conv
+
+	err = db.writeDB(dbStr)
+	return newMsg, err
+}
+
+// GetMessages returns all messages in the given conversation
+func (db *DB) GetMessages(conversationID int) ([]Message, error) {
+	msgs := []Message{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return msgs, err
+	}
+
+	for _, m := range dbStr.Messages {
+		if m.ConversationID == conversationID {
+			msgs = append(msgs, m)
+		}
+	}
+
+	return msgs, err
+}
+
+// MarkConversationRead records that the user has read the conversation up to
+// the given message
+func (db *DB) MarkConversationRead(conversationID int, userID int, messageID int) (Conversation, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return Conversation{}, err
+	}
+
+	conv, ok := dbStr.Conversations[conversationID]
+	if !ok {
+		return Conversation{}, fmt.Errorf("conversation doesn't exist")
+	}
+
+	if conv.LastRead == nil {
+		conv.LastRead = map[int]int{}
+	}
+	if messageID > conv.LastRead[userID] {
+		conv.LastRead[userID] = messageID
+	}
+	dbStr.Conversations[conversationID] = conv
+
+	err = db.writeDB(dbStr)
+	return conv, err
+}
+
+// SetOpenDMs sets whether the user accepts direct messages from users they
+// don't follow
+func (db *DB) SetOpenDMs(userID int, open bool) (User, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return User{}, err
+	}
+
+	u, ok := dbStr.Users[userID]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	u.OpenDMs = open
+	dbStr.Users[userID] = u
+	return u, db.writeDB(dbStr)
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"slices"
+	"time"
+)
+
+// Notification types
+const (
+	NotifyMention = "mention"
+	NotifyReply   = "reply"
+	NotifyLike    = "like"
+	NotifyRechirp = "rechirp"
+	NotifyQuote   = "quote"
+	NotifyFollow  = "follow"
+	// NotifyFollowRequest is sent to protected accounts, NotifyFollowApproved
+	// to the users whose request they approved
+	NotifyFollowRequest  = "follow_request"
+	NotifyFollowApproved = "follow_approved"
+	// NotifyWarning and NotifyChirpRemoved are sent by the moderators and
+	// have no actor
+	NotifyWarning      = "warning"
+	NotifyChirpRemoved = "chirp_removed"
+)
+
+// Notification holds data associated with a notification in the notifications
+// database table. UserID is the recipient and ActorID is the user whose action
+// triggered the notification. ChirpID is the chirp the notification is about,
+// if any, and Note is a message from the moderators.
+type Notification struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	ActorID   int       `json:"actor_id"`
+	Type      string    `json:"type"`
+	ChirpID   int       `json:"chirp_id,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Read      bool      `json:"read"`
+}
+
+// CreateNotification saves a new notification to disk, unless the recipient
+// has muted its type. It returns false if the notification wasn't saved.
+func (db *DB) CreateNotification(n Notification) (Notification, bool, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return n, false, err
+	}
+
+	recipient, ok := dbStr.Users[n.UserID]
+	if !ok || slices.Contains(recipient.MutedNotifications, n.Type) {
+		return n, false, nil
+	}
+
+	n.ID = nextID(dbStr, "notifications", dbStr.Notifications)
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+	dbStr.Notifications[n.ID] = n
+	return n, true, db.writeDB(dbStr)
+}
+
+// GetNotifications returns all notifications of the given user
+func (db *DB) GetNotifications(userID int) ([]Notification, error) {
+	notifications := []Notification{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return notifications, err
+	}
+
+	for _, n := range dbStr.Notifications {
+		if n.UserID == userID {
+			notifications = append(notifications, n)
+		}
+	}
+
+	return notifications, err
+}
+
+// MarkNotificationsRead marks the given notifications of the user as read. If
+// no IDs are given, all of the user's notifications are marked.
+func (db *DB) MarkNotificationsRead(userID int, ids []int) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	for i, n := range dbStr.Notifications {
+		if n.UserID != userID || n.Read {
+			continue
+		}
+		if len(ids) > 0 && !slices.Contains(ids, n.ID) {
+			continue
+		}
+		n.Read = true
+		dbStr.Notifications[i] = n
+	}
+
+	return db.writeDB(dbStr)
+}
+
+// SetMutedNotifications replaces the notification types muted by the user
+func (db *DB) SetMutedNotifications(userID int, types []string) (User, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return User{}, err
+	}
+
+	u, ok := dbStr.Users[userID]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	u.MutedNotifications = types
+	dbStr.Users[userID] = u
+	return u, db.writeDB(dbStr)
+}
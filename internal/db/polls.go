@@ -0,0 +1,131 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Errors returned when voting
+var (
+	ErrAlreadyVoted = errors.New("user already voted")
+	ErrPollClosed   = errors.New("poll has expired")
+)
+
+// Poll holds data associated with a chirp's poll in the polls database table
+type Poll struct {
+	ID        int       `json:"id"`
+	ChirpID   int       `json:"chirp_id"`
+	Options   []string  `json:"options"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Vote holds data associated with a vote in the votes database table. Option
+// is the index of the chosen option in the poll's options.
+type Vote struct {
+	ID        int       `json:"id"`
+	PollID    int       `json:"poll_id"`
+	UserID    int       `json:"user_id"`
+	Option    int       `json:"option"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreatePoll saves a poll for the chirp of the given ID
+func (db *DB) CreatePoll(chirpID int, options []string, expiresAt time.Time) (Poll, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	newPoll := Poll{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return newPoll, err
+	}
+
+	if _, ok := dbStr.Chirps[chirpID]; !ok {
+		return newPoll, fmt.Errorf("chirp doesn't exist")
+	}
+
+	newPoll = Poll{
+		ID:        nextID(dbStr, "polls", dbStr.Polls),
+		ChirpID:   chirpID,
+		Options:   options,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+	dbStr.Polls[newPoll.ID] = newPoll
+	return newPoll, db.writeDB(dbStr)
+}
+
+// GetPolls returns all polls in the database
+func (db *DB) GetPolls() ([]Poll, error) {
+	polls := []Poll{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return polls, err
+	}
+
+	for _, p := range dbStr.Polls {
+		polls = append(polls, p)
+	}
+
+	return polls, err
+}
+
+// CreateVote records the user's vote in the poll of the given ID. The checks
+// for an earlier vote and for the poll's expiry happen under the same lock as
+// the write, so concurrent requests can't vote twice or after expiry.
+func (db *DB) CreateVote(pollID int, userID int, option int) (Vote, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	newVote := Vote{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return newVote, err
+	}
+
+	p, ok := dbStr.Polls[pollID]
+	if !ok {
+		return newVote, fmt.Errorf("poll doesn't exist")
+	}
+	now := time.Now()
+	if !now.Before(p.ExpiresAt) {
+		return newVote, ErrPollClosed
+	}
+
+	for _, v := range dbStr.Votes {
+		if v.PollID == pollID && v.UserID == userID {
+			return newVote, ErrAlreadyVoted
+		}
+	}
+
+	newVote = Vote{
+		ID:        nextID(dbStr, "votes", dbStr.Votes),
+		PollID:    pollID,
+		UserID:    userID,
+		Option:    option,
+		CreatedAt: now,
+	}
+	dbStr.Votes[newVote.ID] = newVote
+	return newVote, db.writeDB(dbStr)
+}
+
+// GetVotes returns all votes in the database
+func (db *DB) GetVotes() ([]Vote, error) {
+	votes := []Vote{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return votes, err
+	}
+
+	for _, v := range dbStr.Votes {
+		votes = append(votes, v)
+	}
+
+	return votes, err
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrHandleTaken is returned when a handle is already used by another user
+// (compared case-insensitively)
+var ErrHandleTaken = errors.New("handle already taken")
+
+// ErrEmailTaken is returned when an email is already used by another user
+// (compared case-insensitively)
+var ErrEmailTaken = errors.New("email already taken")
+
+// emailTaken reports whether a user other than the given one uses the email
+func emailTaken(dbStr dStruct, email string, userID int) bool {
+	for _, u := range dbStr.Users {
+		if u.ID != userID && strings.EqualFold(u.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleTaken reports whether a user other than the given one uses the handle
+func handleTaken(dbStr dStruct, handle string, userID int) bool {
+	for _, u := range dbStr.Users {
+		if u.ID != userID && strings.EqualFold(u.Handle, handle) {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateProfile updates the public profile of the user with the given ID
+func (db *DB) UpdateProfile(userID int, handle string, displayName string, bio string) (User, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return User{}, err
+	}
+
+	u, ok := dbStr.Users[userID]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	if handleTaken(dbStr, handle, userID) {
+		return User{}, ErrHandleTaken
+	}
+
+	u.Handle = handle
+	u.DisplayName = displayName
+	u.Bio = bio
+	dbStr.Users[userID] = u
+	return u, db.writeDB(dbStr)
+}
+
+// SetAvatarURL sets the URL of the avatar of the user with the given ID
+func (db *DB) SetAvatarURL(userID int, avatarURL string) (User, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return User{}, err
+	}
+
+	u, ok := dbStr.Users[userID]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	u.AvatarURL = avatarURL
+	dbStr.Users[userID] = u
+	return u, db.writeDB(dbStr)
+}
+
+// SetProtected sets whether the user's account is protected
+func (db *DB) SetProtected(userID int, protected bool) (User, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return User{}, err
+	}
+
+	u, ok := dbStr.Users[userID]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	u.Protected = protected
+	dbStr.Users[userID] = u
+	return u, db.writeDB(dbStr)
+}
+
+// SetRole sets the user's role
+func (db *DB) SetRole(userID int, role string) (User, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return User{}, err
+	}
+
+	u, ok := dbStr.Users[userID]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	u.Role = role
+	dbStr.Users[userID] = u
+	return u, db.writeDB(dbStr)
+}
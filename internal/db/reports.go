@@ -0,0 +1,216 @@
+package db
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// ErrReportClosed is returned when acting on a report that was already
+// resolved or dismissed
+var ErrReportClosed = errors.New("report already closed")
+
+// Report statuses. Open reports are in the moderation queue, resolved reports
+// had action taken on them and dismissed reports didn't need any.
+const (
+	ReportOpen      = "open"
+	ReportResolved  = "resolved"
+	ReportDismissed = "dismissed"
+)
+
+// Moderation actions. The first four are taken on reports, the rest directly
+// on accounts.
+const (
+	ModRemove      = "remove"
+	ModWarn        = "warn"
+	ModSuspend     = "suspend"
+	ModDismiss     = "dismiss"
+	ModUnsuspend   = "unsuspend"
+	ModShadowBan   = "shadow_ban"
+	ModUnshadowBan = "unshadow_ban"
+)
+
+// Report holds data associated with a report in the reports database table.
+// UserID is the reported account, and for reports of a chirp, ChirpID is the
+// chirp and ChirpBody its body at the time it was reported.
+type Report struct {
+	ID         int        `json:"id"`
+	ReporterID int        `json:"reporter_id"`
+	UserID     int        `json:"user_id"`
+	ChirpID    int        `json:"chirp_id,omitempty"`
+	ChirpBody  string     `json:"chirp_body,omitempty"`
+	Reason     string     `json:"reason"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ClosedAt   *time.Time `json:"closed_at,omitempty"`
+}
+
+// ModAction holds data associated with a moderation action in the
+// moderation_actions database table. ReportID is 0 for actions taken directly
+// on an account.
+type ModAction struct {
+	ID          int        `json:"id"`
+	ReportID    int        `json:"report_id"`
+	ModeratorID int        `json:"moderator_id"`
+	Action      string     `json:"action"`
+	UserID      int        `json:"user_id"`
+	ChirpID     int        `json:"chirp_id,omitempty"`
+	Note        string     `json:"note,omitempty"`
+	Until       *time.Time `json:"until,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreateReport saves a new open report, unless the reporter already has an
+// open report of the same chirp or account. It returns false if the report
+// wasn't saved.
+func (db *DB) CreateReport(r Report) (Report, bool, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return r, false, err
+	}
+
+	for _, existing := range dbStr.Reports {
+		if existing.ReporterID == r.ReporterID && existing.UserID == r.UserID &&
+			existing.ChirpID == r.ChirpID && existing.Status == ReportOpen {
+			return existing, false, nil
+		}
+	}
+
+	r.ID = nextID(dbStr, "reports", dbStr.Reports)
+	r.Status = ReportOpen
+	r.CreatedAt = time.Now()
+	dbStr.Reports[r.ID] = r
+	return r, true, db.writeDB(dbStr)
+}
+
+// GetReports returns all reports in the database
+func (db *DB) GetReports() ([]Report, error) {
+	reports := []Report{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return reports, err
+	}
+
+	for _, r := range dbStr.Reports {
+		reports = append(reports, r)
+	}
+
+	return reports, err
+}
+
+// CreateModAction records a moderation action. Actions taken on a report
+// close it with the given status, along with the other open reports in
+// closeIDs. Reports can only be acted on while they're open.
+func (db *DB) CreateModAction(a ModAction, status string, closeIDs []int) (ModAction, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return a, err
+	}
+
+	if a.ReportID != 0 && dbStr.Reports[a.ReportID].Status != ReportOpen {
+		return a, ErrReportClosed
+	}
+
+	a.ID = nextID(dbStr, "moderation_actions", dbStr.ModActions)
+	a.CreatedAt = time.Now()
+	dbStr.ModActions[a.ID] = a
+
+	for id, r := range dbStr.Reports {
+		if a.ReportID == 0 || r.Status != ReportOpen || (id != a.ReportID && !slices.Contains(closeIDs, id)) {
+			continue
+		}
+		r.Status = status
+		r.ClosedAt = &a.CreatedAt
+		dbStr.Reports[id] = r
+	}
+
+	return a, db.writeDB(dbStr)
+}
+
+// GetModActions returns all moderation actions in the database
+func (db *DB) GetModActions() ([]ModAction, error) {
+	actions := []ModAction{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return actions, err
+	}
+
+	for _, a := range dbStr.ModActions {
+		actions = append(actions, a)
+	}
+
+	return actions, err
+}
+
+// SuspendUser suspends the user until the given time, or indefinitely if
+// until is nil
+func (db *DB) SuspendUser(userID int, until *time.Time, reason string) (User, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return User{}, err
+	}
+
+	u, ok := dbStr.Users[userID]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	u.Suspended = true
+	u.SuspendedUntil = until
+	u.SuspensionReason = reason
+	dbStr.Users[userID] = u
+	return u, db.writeDB(dbStr)
+}
+
+// UnsuspendUser lifts the user's suspension
+func (db *DB) UnsuspendUser(userID int) (User, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return User{}, err
+	}
+
+	u, ok := dbStr.Users[userID]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	u.Suspended = false
+	u.SuspendedUntil = nil
+	u.SuspensionReason = ""
+	dbStr.Users[userID] = u
+	return u, db.writeDB(dbStr)
+}
+
+// SetShadowBanned sets whether the user is shadow-banned
+func (db *DB) SetShadowBanned(userID int, banned bool) (User, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return User{}, err
+	}
+
+	u, ok := dbStr.Users[userID]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	u.ShadowBanned = banned
+	dbStr.Users[userID] = u
+	return u, db.writeDB(dbStr)
+}
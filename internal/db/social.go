@@ -0,0 +1,243 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Follow holds data associated with a follow in the follows database table
+type Follow struct {
+	ID         int       `json:"id"`
+	FollowerID int       `json:"follower_id"`
+	FolloweeID int       `json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FollowRequest holds data associated with a pending follow of a protected
+// account in the follow_requests database table
+type FollowRequest struct {
+	ID         int       `json:"id"`
+	FollowerID int       `json:"follower_id"`
+	FolloweeID int       `json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Like holds data associated with a like in the likes database table
+type Like struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	ChirpID   int       `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateFollow makes the follower follow the followee. It returns false if the
+// follow already existed.
+func (db *DB) CreateFollow(followerID int, followeeID int) (Follow, bool, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return Follow{}, false, err
+	}
+
+	for _, f := range dbStr.Follows {
+		if f.FollowerID == followerID && f.FolloweeID == followeeID {
+			return f, false, nil
+		}
+	}
+
+	newFollow := Follow{
+		ID:         nextID(dbStr, "follows", dbStr.Follows),
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+		CreatedAt:  time.Now(),
+	}
+	dbStr.Follows[newFollow.ID] = newFollow
+	err = db.writeDB(dbStr)
+	return newFollow, true, err
+}
+
+// DeleteFollow makes the follower stop following the followee
+func (db *DB) DeleteFollow(followerID int, followeeID int) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	for i, f := range dbStr.Follows {
+		if f.FollowerID == followerID && f.FolloweeID == followeeID {
+			delete(dbStr.Follows, i)
+			return db.writeDB(dbStr)
+		}
+	}
+
+	return fmt.Errorf("follow doesn't exist")
+}
+
+// GetFollows returns all follows in the database
+func (db *DB) GetFollows() ([]Follow, error) {
+	follows := []Follow{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return follows, err
+	}
+
+	for _, f := range dbStr.Follows {
+		follows = append(follows, f)
+	}
+
+	return follows, err
+}
+
+// CreateFollowRequest asks the followee to approve the follower. It returns
+// false if the request (or the follow itself) already existed.
+func (db *DB) CreateFollowRequest(followerID int, followeeID int) (FollowRequest, bool, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return FollowRequest{}, false, err
+	}
+
+	for _, f := range dbStr.Follows {
+		if f.FollowerID == followerID && f.FolloweeID == followeeID {
+			return FollowRequest{}, false, nil
+		}
+	}
+	for _, r := range dbStr.FollowRequests {
+		if r.FollowerID == followerID && r.FolloweeID == followeeID {
+			return r, false, nil
+		}
+	}
+
+	newRequest := FollowRequest{
+		ID:         nextID(dbStr, "follow_requests", dbStr.FollowRequests),
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+		CreatedAt:  time.Now(),
+	}
+	dbStr.FollowRequests[newRequest.ID] = newRequest
+	err = db.writeDB(dbStr)
+	return newRequest, true, err
+}
+
+// DeleteFollowRequest removes the follow request of the given ID
+func (db *DB) DeleteFollowRequest(id int) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := dbStr.FollowRequests[id]; !ok {
+		return fmt.Errorf("follow request doesn't exist")
+	}
+
+	delete(dbStr.FollowRequests, id)
+	return db.writeDB(dbStr)
+}
+
+// GetFollowRequests returns all follow requests in the database
+func (db *DB) GetFollowRequests() ([]FollowRequest, error) {
+	requests := []FollowRequest{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return requests, err
+	}
+
+	for _, r := range dbStr.FollowRequests {
+		requests = append(requests, r)
+	}
+
+	return requests, err
+}
+
+// ApproveFollowRequests turns the follow requests of the given IDs into
+// follows, returning the created follows
+func (db *DB) ApproveFollowRequests(ids []int) ([]Follow, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	follows := []Follow{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return follows, err
+	}
+
+	for _, id := range ids {
+		r, ok := dbStr.FollowRequests[id]
+		if !ok {
+			return follows, fmt.Errorf("follow request doesn't exist")
+		}
+		delete(dbStr.FollowRequests, id)
+
+		newFollow := Follow{
+			ID:         nextID(dbStr, "follows", dbStr.Follows),
+			FollowerID: r.FollowerID,
+			FolloweeID: r.FolloweeID,
+			CreatedAt:  time.Now(),
+		}
+		dbStr.Follows[newFollow.ID] = newFollow
+		follows = append(follows, newFollow)
+	}
+
+	return follows, db.writeDB(dbStr)
+}
+
+// CreateLike makes the user like the chirp. It returns false if the like
+// already existed.
+func (db *DB) CreateLike(userID int, chirpID int) (Like, bool, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return Like{}, false, err
+	}
+
+	for _, l := range dbStr.Likes {
+		if l.UserID == userID && l.ChirpID == chirpID {
+			return l, false, nil
+		}
+	}
+
+	newLike := Like{
+		ID:        nextID(dbStr, "likes", dbStr.Likes),
+		UserID:    userID,
+		ChirpID:   chirpID,
+		CreatedAt: time.Now(),
+	}
+	dbStr.Likes[newLike.ID] = newLike
+	err = db.writeDB(dbStr)
+	return newLike, true, err
+}
+
+// DeleteLike removes the user's like from the chirp
+func (db *DB) DeleteLike(userID int, chirpID int) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+
+	for i, l := range dbStr.Likes {
+		if l.UserID == userID && l.ChirpID == chirpID {
+			delete(dbStr.Likes, i)
+			return db.writeDB(dbStr)
+		}
+	}
+
+	return fmt.Errorf("like doesn't exist")
+}
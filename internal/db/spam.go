@@ -0,0 +1,106 @@
+package db
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrVerdictReviewed is returned when reviewing a spam verdict that was
+// already reviewed
+var ErrVerdictReviewed = errors.New("spam verdict already reviewed")
+
+// Spam verdict reviews. Approved held chirps are posted, removed ones are
+// deleted.
+const (
+	ReviewApproved = "approved"
+	ReviewRemoved  = "removed"
+)
+
+// SpamSignal is a spam signal that fired for a chirp along with the points it
+// added to the chirp's score
+type SpamSignal struct {
+	Name   string `json:"name"`
+	Points int    `json:"points"`
+	Detail string `json:"detail"`
+}
+
+// SpamVerdict holds data associated with a chirp's spam score in the
+// spam_verdicts database table. Rejected chirps were never saved, so ChirpID
+// is 0 for them and Body is all that's left. Review is set once a moderator
+// has reviewed a held chirp.
+type SpamVerdict struct {
+	ID         int          `json:"id"`
+	AuthorID   int          `json:"author_id"`
+	ChirpID    int          `json:"chirp_id,omitempty"`
+	Body       string       `json:"body"`
+	Score      int          `json:"score"`
+	Verdict    string       `json:"verdict"`
+	Signals    []SpamSignal `json:"signals"`
+	CreatedAt  time.Time    `json:"created_at"`
+	Review     string       `json:"review,omitempty"`
+	ReviewerID int          `json:"reviewer_id,omitempty"`
+	ReviewedAt *time.Time   `json:"reviewed_at,omitempty"`
+}
+
+// CreateSpamVerdict saves a new spam verdict
+func (db *DB) CreateSpamVerdict(v SpamVerdict) (SpamVerdict, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return v, err
+	}
+
+	v.ID = nextID(dbStr, "spam_verdicts", dbStr.SpamVerdicts)
+	v.CreatedAt = time.Now()
+	dbStr.SpamVerdicts[v.ID] = v
+	return v, db.writeDB(dbStr)
+}
+
+// GetSpamVerdicts returns all spam verdicts in the database
+func (db *DB) GetSpamVerdicts() ([]SpamVerdict, error) {
+	verdicts := []SpamVerdict{}
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return verdicts, err
+	}
+
+	for _, v := range dbStr.SpamVerdicts {
+		verdicts = append(verdicts, v)
+	}
+
+	return verdicts, err
+}
+
+// ReviewSpamVerdict records a moderator's review of a spam verdict. Approving
+// it releases the held chirp in the same write. Verdicts can only be reviewed
+// once.
+func (db *DB) ReviewSpamVerdict(verdictID int, review string, reviewerID int) (SpamVerdict, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbStr, err := db.loadDB()
+	if err != nil {
+		return SpamVerdict{}, err
+	}
+
+	v := dbStr.SpamVerdicts[verdictID]
+	if v.Review != "" {
+		return v, ErrVerdictReviewed
+	}
+
+	now := time.Now()
+	v.Review = review
+	v.ReviewerID = reviewerID
+	v.ReviewedAt = &now
+	dbStr.SpamVerdicts[verdictID] = v
+
+	if c, ok := dbStr.Chirps[v.ChirpID]; ok && review == ReviewApproved {
+		c.Held = false
+		dbStr.Chirps[c.ID] = c
+	}
+
+	return v, db.writeDB(dbStr)
+}
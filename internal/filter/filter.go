@@ -0,0 +1,285 @@
+// Package filter checks chirp bodies against a configurable list of banned
+// words. Words are matched after normalisation, so case, diacritics, common
+// leetspeak substitutions and surrounding punctuation don't let a banned word
+// through.
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/wipdev-tech/chirpy/internal/text"
+)
+
+// Actions taken when a chirp contains a banned word. Masked words are
+// replaced with asterisks, rejected chirps aren't saved at all and flagged
+// chirps are saved as-is but recorded for review.
+const (
+	ActionMask   = "mask"
+	ActionReject = "reject"
+	ActionFlag   = "flag"
+)
+
+// Mask is what a masked word is replaced with
+const Mask = "****"
+
+// ErrInvalidRule is returned for rules with an unknown action or a word that
+// doesn't contain any letters or digits
+var ErrInvalidRule = errors.New("invalid filter rule")
+
+// Rule is a banned word and the action taken when a chirp contains it
+type Rule struct {
+	Word   string `json:"word"`
+	Action string `json:"action"`
+}
+
+// DefaultRules are used when no config file is given
+var DefaultRules = []Rule{
+	{Word: "kerfuffle", Action: ActionMask},
+	{Word: "sharbert", Action: ActionMask},
+	{Word: "fornax", Action: ActionMask},
+}
+
+// Result is the outcome of checking a chirp body. Body has the masked words
+// replaced, and Flagged lists the words that matched flag rules.
+type Result struct {
+	Body     string
+	Rejected bool
+	Flagged  []string
+}
+
+// Filter holds the current rules. It is safe for concurrent use.
+type Filter struct {
+	mux   sync.RWMutex
+	rules map[string]Rule
+}
+
+// New creates a filter with the given rules
+func New(rules []Rule) (*Filter, error) {
+	f := &Filter{rules: map[string]Rule{}}
+	for _, r := range rules {
+		_, err := f.Set(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// LoadConfig reads rules from a JSON config file of the form
+// {"rules": [{"word": "kerfuffle", "action": "mask"}]}
+func LoadConfig(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := struct {
+		Rules []Rule `json:"rules"`
+	}{}
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, err
+	}
+	return config.Rules, nil
+}
+
+// Normalize returns the form of a word that rules are matched on: lowercased,
+// without diacritics and with anything other than letters and digits removed
+func Normalize(word string) string {
+	var b strings.Builder
+	for _, r := range text.Fold(word) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Rules returns the current rules sorted by word
+func (f *Filter) Rules() []Rule {
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+
+	rules := []Rule{}
+	for _, r := range f.rules {
+		rules = append(rules, r)
+	}
+	slices.SortFunc(rules, func(a, b Rule) int { return strings.Compare(a.Word, b.Word) })
+	return rules
+}
+
+// Set adds a rule or replaces the rule for the same word, returning the rule
+// as stored (with the word normalised)
+func (f *Filter) Set(rule Rule) (Rule, error) {
+	rule.Word = Normalize(rule.Word)
+	if rule.Word == "" {
+		return rule, ErrInvalidRule
+	}
+	switch rule.Action {
+	case ActionMask, ActionReject, ActionFlag:
+	default:
+		return rule, ErrInvalidRule
+	}
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.rules[rule.Word] = rule
+	return rule, nil
+}
+
+// Remove deletes the rule for the given word, reporting whether there was one
+func (f *Filter) Remove(word string) bool {
+	word = Normalize(word)
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	_, ok := f.rules[word]
+	delete(f.rules, word)
+	return ok
+}
+
+// Check matches every word of the body against the rules. Everything other
+// than masked words, including whitespace and line breaks, is kept as-is.
+func (f *Filter) Check(body string) Result {
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+
+	res := Result{Flagged: []string{}}
+	var b strings.Builder
+	runes := []rune(body)
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+
+		start, stop, rule, ok := f.match(runes[i:end])
+		if !ok {
+			b.WriteString(string(runes[i:end]))
+			i = end
+			continue
+		}
+
+		switch rule.Action {
+		case ActionReject:
+			res.Rejected = true
+		case ActionFlag:
+			if !slices.Contains(res.Flagged, rule.Word) {
+				res.Flagged = append(res.Flagged, rule.Word)
+			}
+		}
+
+		if rule.Action == ActionMask {
+			b.WriteString(string(runes[i : i+start]))
+			b.WriteString(Mask)
+			b.WriteString(string(runes[i+stop : end]))
+		} else {
+			b.WriteString(string(runes[i:end]))
+		}
+		i = end
+	}
+
+	res.Body = b.String()
+	return res
+}
+
+// match looks for a rule matching the token. Leading and trailing symbols may
+// be either leetspeak ("$harbert") or punctuation ("kerfuffle!"), so the
+// widest match is tried first before trimming them. start and stop are the
+// bounds of the matched part of the token.
+func (f *Filter) match(token []rune) (start int, stop int, rule Rule, ok bool) {
+	lead := 0
+	for lead < len(token) && isSymbol(token[lead]) {
+		lead++
+	}
+	trail := len(token)
+	for trail > lead && isSymbol(token[trail-1]) {
+		trail--
+	}
+
+	for i := 0; i <= lead; i++ {
+		for j := len(token); j >= trail && j > i; j-- {
+			for _, r := range f.rules {
+				if matches(token[i:j], r.Word) {
+					return i, j, r, true
+				}
+			}
+		}
+	}
+	return 0, 0, Rule{}, false
+}
+
+// matches reports whether the token spells the normalised word, with
+// leetspeak substitutions allowed and combining marks and invisible
+// formatting characters ignored
+func matches(token []rune, word string) bool {
+	wordRunes := []rune(word)
+	i := 0
+	for _, r := range token {
+		if unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		if i == len(wordRunes) {
+			return false
+		}
+
+		// A single character may fold to several, such as a ligature
+		folded := []rune(text.Fold(string(r)))
+		if len(folded) > 0 && len(folded) <= len(wordRunes)-i && slices.Equal(folded, wordRunes[i:i+len(folded)]) {
+			i += len(folded)
+			continue
+		}
+		if !slices.Contains(leet[r], wordRunes[i]) {
+			return false
+		}
+		i++
+	}
+	return i == len(wordRunes)
+}
+
+// leet maps digits and symbols to the letters they commonly stand in for
+var leet = map[rune][]rune{
+	'0': {'o'},
+	'1': {'i', 'l'},
+	'2': {'z'},
+	'3': {'e'},
+	'4': {'a'},
+	'5': {'s'},
+	'6': {'g'},
+	'7': {'t'},
+	'8': {'b'},
+	'9': {'g'},
+	'@': {'a'},
+	'$': {'s'},
+	'!': {'i', 'l'},
+	'|': {'i', 'l'},
+	'+': {'t'},
+	'€': {'e'},
+	'£': {'l'},
+}
+
+// isSymbol reports whether the rune is a leetspeak symbol rather than a
+// letter or digit
+func isSymbol(r rune) bool {
+	_, ok := leet[r]
+	return ok && !unicode.IsDigit(r)
+}
+
+// isWordRune reports whether the rune can be part of a word. Whitespace and
+// punctuation that isn't used in leetspeak separate words.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || isSymbol(r) ||
+		unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r)
+}
@@ -0,0 +1,131 @@
+package filter
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func newTestFilter(t *testing.T) *Filter {
+	t.Helper()
+	f, err := New([]Rule{
+		{Word: "kerfuffle", Action: ActionMask},
+		{Word: "sharbert", Action: ActionMask},
+		{Word: "fornax", Action: ActionReject},
+		{Word: "spoiler", Action: ActionFlag},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Kerfuffle", "kerfuffle"},
+		{"  kérfüffle! ", "kerfuffle"},
+		{"foo-bar", "foobar"},
+		{"ＦＯＲＮＡＸ", "fornax"},
+		{"!!!", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		rule    Rule
+		want    Rule
+		wantErr error
+	}{
+		{Rule{Word: "Gosh", Action: ActionMask}, Rule{Word: "gosh", Action: ActionMask}, nil},
+		{Rule{Word: "gosh", Action: "shout"}, Rule{}, ErrInvalidRule},
+		{Rule{Word: "?!", Action: ActionMask}, Rule{}, ErrInvalidRule},
+		{Rule{Word: "", Action: ActionFlag}, Rule{}, ErrInvalidRule},
+	}
+	for _, tt := range tests {
+		f := newTestFilter(t)
+		got, err := f.Set(tt.rule)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Set(%+v) error = %v, want %v", tt.rule, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("Set(%+v) = %+v, want %+v", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	f := newTestFilter(t)
+	if !f.Remove("KERFUFFLE") {
+		t.Error("Remove of an existing rule returned false")
+	}
+	if f.Remove("kerfuffle") {
+		t.Error("Remove of a removed rule returned true")
+	}
+	if got := f.Check("kerfuffle").Body; got != "kerfuffle" {
+		t.Errorf("removed word was still masked: %q", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantBody     string
+		wantRejected bool
+		wantFlagged  []string
+	}{
+		{"clean", "hello world", "hello world", false, nil},
+		{"mask", "what a kerfuffle", "what a ****", false, nil},
+		{"case", "What a KerFuffle", "What a ****", false, nil},
+		{"diacritics", "what a kérfüffle", "what a ****", false, nil},
+		{"combining marks", "what a ke\u0301rfuffle", "what a ****", false, nil},
+		{"invisible characters", "what a ker\u200bfuffle", "what a ****", false, nil},
+		{"leetspeak", "k3rfuff1e", "****", false, nil},
+		{"leading symbol", "$harbert", "****", false, nil},
+		{"trailing punctuation", "kerfuffle!", "****!", false, nil},
+		{"surrounding punctuation", "(sharbert)", "(****)", false, nil},
+		{"ligature", "kerfu\ufb04e", "****", false, nil},
+		{"inside a word", "kerfuffles", "kerfuffles", false, nil},
+		{"whitespace kept", "a\n\tkerfuffle  b", "a\n\t****  b", false, nil},
+		{"reject", "fornax is here", "fornax is here", true, nil},
+		{"reject leetspeak", "f0rn@x", "f0rn@x", true, nil},
+		{"flag", "spoiler: spoiler", "spoiler: spoiler", false, []string{"spoiler"}},
+		{"mixed", "kerfuffle spoiler", "**** spoiler", false, []string{"spoiler"}},
+	}
+	f := newTestFilter(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := f.Check(tt.body)
+			if res.Body != tt.wantBody {
+				t.Errorf("Body = %q, want %q", res.Body, tt.wantBody)
+			}
+			if res.Rejected != tt.wantRejected {
+				t.Errorf("Rejected = %v, want %v", res.Rejected, tt.wantRejected)
+			}
+			if !slices.Equal(res.Flagged, tt.wantFlagged) && !(len(res.Flagged) == 0 && len(tt.wantFlagged) == 0) {
+				t.Errorf("Flagged = %q, want %q", res.Flagged, tt.wantFlagged)
+			}
+		})
+	}
+}
+
+func TestRules(t *testing.T) {
+	f := newTestFilter(t)
+	got := []string{}
+	for _, r := range f.Rules() {
+		got = append(got, r.Word)
+	}
+	want := []string{"fornax", "kerfuffle", "sharbert", "spoiler"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Rules() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,145 @@
+// Package graphemes splits text into user-perceived characters (extended
+// grapheme clusters). It implements the parts of the Unicode segmentation
+// rules (UAX #29) that matter for chirps: combining marks, emoji modifier
+// and ZWJ sequences, flags, variation selectors, Hangul syllables and CRLF.
+package graphemes
+
+import "unicode"
+
+const (
+	zwj = '\u200d'
+	cr  = '\r'
+	lf  = '\n'
+)
+
+// Count returns the number of grapheme clusters in the text
+func Count(text string) int {
+	return len(Split(text))
+}
+
+// Split returns the grapheme clusters of the text in order
+func Split(text string) []string {
+	runes := []rune(text)
+	clusters := []string{}
+	for start := 0; start < len(runes); {
+		end := start + 1
+		for end < len(runes) && !isBoundary(runes[start:end], runes[end]) {
+			end++
+		}
+		clusters = append(clusters, string(runes[start:end]))
+		start = end
+	}
+	return clusters
+}
+
+// isBoundary reports whether a new cluster starts with next, given the runes
+// of the current cluster
+func isBoundary(cluster []rune, next rune) bool {
+	prev := cluster[len(cluster)-1]
+
+	// CR LF is a single cluster, otherwise control characters stand alone
+	if prev == cr && next == lf {
+		return false
+	}
+	if isControl(prev) || isControl(next) {
+		return true
+	}
+
+	// Hangul syllables are made of leading, vowel and trailing jamo
+	if isHangulL(prev) && (isHangulL(next) || isHangulV(next) || isHangulLV(next) || isHangulLVT(next)) {
+		return false
+	}
+	if (isHangulLV(prev) || isHangulV(prev)) && (isHangulV(next) || isHangulT(next)) {
+		return false
+	}
+	if (isHangulLVT(prev) || isHangulT(prev)) && isHangulT(next) {
+		return false
+	}
+
+	// Marks, joiners and modifiers attach to whatever precedes them
+	if isExtend(next) || next == zwj || unicode.Is(unicode.Mc, next) {
+		return false
+	}
+
+	// An emoji joined to a previous one by a ZWJ continues the sequence
+	if prev == zwj && isPictographic(next) && startsWithPictographic(cluster) {
+		return false
+	}
+
+	// Regional indicators pair up into flags
+	if isRegionalIndicator(prev) && isRegionalIndicator(next) {
+		count := 0
+		for i := len(cluster) - 1; i >= 0 && isRegionalIndicator(cluster[i]); i-- {
+			count++
+		}
+		return count%2 == 0
+	}
+
+	return true
+}
+
+// startsWithPictographic reports whether the cluster is an emoji sequence
+func startsWithPictographic(cluster []rune) bool {
+	for _, r := range cluster {
+		if isPictographic(r) {
+			return true
+		}
+		if !isExtend(r) && r != zwj {
+			return false
+		}
+	}
+	return false
+}
+
+func isControl(r rune) bool {
+	return r == cr || r == lf || (unicode.IsControl(r) && r != zwj)
+}
+
+// isExtend reports whether the rune extends the previous character: combining
+// marks, variation selectors, emoji skin tone modifiers and tag characters
+func isExtend(r rune) bool {
+	return unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) ||
+		(r >= 0xfe00 && r <= 0xfe0f) ||
+		(r >= 0xe0100 && r <= 0xe01ef) ||
+		(r >= 0x1f3fb && r <= 0x1f3ff) ||
+		(r >= 0xe0020 && r <= 0xe007f) ||
+		r == '\u200c'
+}
+
+// isPictographic approximates the Extended_Pictographic property with the
+// blocks emoji are allocated in
+func isPictographic(r rune) bool {
+	return r == 0xa9 || r == 0xae ||
+		(r >= 0x2190 && r <= 0x21ff) ||
+		(r >= 0x2300 && r <= 0x23ff) ||
+		(r >= 0x2600 && r <= 0x27bf) ||
+		(r >= 0x2b00 && r <= 0x2bff) ||
+		(r >= 0x1f000 && r <= 0x1faff)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+func isHangulL(r rune) bool {
+	return (r >= 0x1100 && r <= 0x115f) || (r >= 0xa960 && r <= 0xa97c)
+}
+
+func isHangulV(r rune) bool {
+	return (r >= 0x1160 && r <= 0x11a7) || (r >= 0xd7b0 && r <= 0xd7c6)
+}
+
+func isHangulT(r rune) bool {
+	return (r >= 0x11a8 && r <= 0x11ff) || (r >= 0xd7cb && r <= 0xd7fb)
+}
+
+// isHangulLV reports whether the rune is a precomposed syllable without a
+// trailing consonant. Syllables come in blocks of 28, the first of which has
+// no trailing consonant.
+func isHangulLV(r rune) bool {
+	return r >= 0xac00 && r <= 0xd7a3 && (r-0xac00)%28 == 0
+}
+
+func isHangulLVT(r rune) bool {
+	return r >= 0xac00 && r <= 0xd7a3 && (r-0xac00)%28 != 0
+}
@@ -0,0 +1,48 @@
+package graphemes
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"empty", "", []string{}},
+		{"ASCII", "abc", []string{"a", "b", "c"}},
+		{"precomposed accent", "\u00e9", []string{"\u00e9"}},
+		{"combining accent", "e\u0301x", []string{"e\u0301", "x"}},
+		{"several combining marks", "a\u0301\u0302\u0303", []string{"a\u0301\u0302\u0303"}},
+		{"spacing mark", "\u0915\u093e", []string{"\u0915\u093e"}},
+		{"CRLF", "a\r\nb", []string{"a", "\r\n", "b"}},
+		{"LFCR", "\n\r", []string{"\n", "\r"}},
+		{"control before mark", "\n\u0301", []string{"\n", "\u0301"}},
+		{"skin tone", "👍🏽!", []string{"👍🏽", "!"}},
+		{"variation selector", "\u2764\ufe0f", []string{"\u2764\ufe0f"}},
+		{"ZWJ family", "\U0001F468\u200d\U0001F469\u200d\U0001F467", []string{"\U0001F468\u200d\U0001F469\u200d\U0001F467"}},
+		{"ZWJ with skin tones", "\U0001F469\U0001F3FD\u200d\U0001F4BB", []string{"\U0001F469\U0001F3FD\u200d\U0001F4BB"}},
+		{"ZWJ after a letter", "a\u200d\U0001F44D", []string{"a\u200d", "\U0001F44D"}},
+		{"flag", "🇫🇷", []string{"🇫🇷"}},
+		{"two flags", "🇫🇷🇩🇪", []string{"🇫🇷", "🇩🇪"}},
+		{"odd regional indicator", "🇫🇷🇩", []string{"🇫🇷", "🇩"}},
+		{"tag sequence", "🏴\U000E0067\U000E0062\U000E0065\U000E006E\U000E0067\U000E007F", []string{"🏴\U000E0067\U000E0062\U000E0065\U000E006E\U000E0067\U000E007F"}},
+		{"Hangul syllables", "한국어", []string{"한", "국", "어"}},
+		{"Hangul jamo", "\u1100\u1161\u11a8", []string{"\u1100\u1161\u11a8"}},
+		{"Hangul LV and T", "\uac00\u11a8", []string{"\uac00\u11a8"}},
+		{"CJK", "日本", []string{"日", "本"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Split(tt.text)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Split(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if n := Count(tt.text); n != len(tt.want) {
+				t.Errorf("Count(%q) = %d, want %d", tt.text, n, len(tt.want))
+			}
+		})
+	}
+}
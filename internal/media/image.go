@@ -0,0 +1,318 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// Image processing limits
+const (
+	MaxImageSize     = 5 << 20
+	maxPixels        = 40_000_000
+	thumbnailSize    = 320
+	jpegQuality      = 85
+	thumbnailQuality = 80
+)
+
+// ErrUnsupportedImage is returned for uploads that aren't PNG, JPEG or GIF
+// images, or are too large to process
+var ErrUnsupportedImage = errors.New("unsupported image")
+
+// Processed holds an uploaded image after processing, along with its
+// thumbnail
+type Processed struct {
+	Data        []byte
+	Thumbnail   []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+}
+
+// Process validates an uploaded image and re-encodes it, which strips any
+// metadata (such as EXIF location data) the original carried. JPEGs are
+// rotated according to their EXIF orientation first, since it's lost along
+// with the rest of the metadata. It also generates a thumbnail fitting in a
+// 320x320 square.
+func Process(data []byte) (Processed, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width*cfg.Height > maxPixels {
+		return Processed{}, ErrUnsupportedImage
+	}
+
+	out := Processed{Width: cfg.Width, Height: cfg.Height}
+	var img image.Image
+	buf := &bytes.Buffer{}
+
+	switch format {
+	case "jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err == nil {
+			img = orient(img, jpegOrientation(data))
+			out.Width, out.Height = img.Bounds().Dx(), img.Bounds().Dy()
+			err = jpeg.Encode(buf, img, &jpeg.Options{Quality: jpegQuality})
+		}
+		out.ContentType, out.Ext = "image/jpeg", ".jpg"
+	case "png":
+		img, err = png.Decode(bytes.NewReader(data))
+		if err == nil {
+			err = png.Encode(buf, img)
+		}
+		out.ContentType, out.Ext = "image/png", ".png"
+	case "gif":
+		// Every frame is decoded, so the pixel limit applies to all of them
+		// together rather than to the first one only
+		if gifPixels(data) > maxPixels {
+			return Processed{}, ErrUnsupportedImage
+		}
+		var g *gif.GIF
+		g, err = gif.DecodeAll(bytes.NewReader(data))
+		if err == nil {
+			img = g.Image[0]
+			err = gif.EncodeAll(buf, g)
+		}
+		out.ContentType, out.Ext = "image/gif", ".gif"
+	default:
+		return Processed{}, ErrUnsupportedImage
+	}
+	if err != nil {
+		return Processed{}, ErrUnsupportedImage
+	}
+	out.Data = buf.Bytes()
+
+	thumbBuf := &bytes.Buffer{}
+	thumb := resize(img, thumbnailSize)
+	if format == "jpeg" {
+		err = jpeg.Encode(thumbBuf, thumb, &jpeg.Options{Quality: thumbnailQuality})
+	} else {
+		err = png.Encode(thumbBuf, thumb)
+	}
+	if err != nil {
+		return Processed{}, err
+	}
+	out.Thumbnail = thumbBuf.Bytes()
+
+	return out, nil
+}
+
+// resize scales an image down (never up) to fit in a size x size square,
+// averaging the source pixels covered by every destination pixel
+func resize(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+
+	dw, dh := size, h*size/w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*h/dh, b.Min.Y+max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*w/dw, b.Min.X+max((x+1)*w/dw, x*w/dw+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			// Colors are premultiplied 16-bit values; NRGBA wants 8-bit
+			// non-premultiplied ones
+			if a == 0 {
+				continue
+			}
+			dst.Pix[i+0] = uint8(r * 0xFF / a)
+			dst.Pix[i+1] = uint8(g * 0xFF / a)
+			dst.Pix[i+2] = uint8(bl * 0xFF / a)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
+
+// gifPixels walks the blocks of a GIF without decoding it and adds up the
+// pixels of all its frames. Malformed GIFs count as too large.
+func gifPixels(data []byte) int {
+	const tooLarge = maxPixels + 1
+
+	// Header and logical screen descriptor, followed by the global color
+	// table if there is one
+	pos := 13
+	if len(data) < pos {
+		return tooLarge
+	}
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&0x07 + 1)
+	}
+
+	// skipSubBlocks skips a sequence of data sub-blocks ending in an empty one
+	skipSubBlocks := func() bool {
+		for pos < len(data) {
+			size := int(data[pos])
+			pos += size + 1
+			if size == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	pixels := 0
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // Extension
+			pos += 2
+			if !skipSubBlocks() {
+				return tooLarge
+			}
+		case 0x2C: // Image descriptor
+			if pos+10 > len(data) {
+				return tooLarge
+			}
+			w := int(binary.LittleEndian.Uint16(data[pos+5:]))
+			h := int(binary.LittleEndian.Uint16(data[pos+7:]))
+			pixels += w * h
+			if pixels > maxPixels {
+				return tooLarge
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			// LZW minimum code size, then the image data
+			pos++
+			if !skipSubBlocks() {
+				return tooLarge
+			}
+		case 0x3B: // Trailer
+			return pixels
+		default:
+			return tooLarge
+		}
+	}
+	return tooLarge
+}
+
+// jpegOrientation reads the EXIF orientation of a JPEG, which is 1 (no
+// transformation needed) if it has none
+func jpegOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		// Start of scan: the metadata segments all come before it
+		if marker == 0xDA {
+			return 1
+		}
+		// Restart markers stand alone, without a length
+		if marker >= 0xD0 && marker <= 0xD7 {
+			pos += 2
+			continue
+		}
+		// The length counts itself, so anything shorter than 2 or running
+		// past the end means the segments can't be trusted
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		pos += 2 + length
+
+		if marker != 0xE1 || !bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			continue
+		}
+		tiff := segment[6:]
+		if len(tiff) < 8 {
+			return 1
+		}
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case "II":
+			order = binary.LittleEndian
+		case "MM":
+			order = binary.BigEndian
+		default:
+			return 1
+		}
+
+		ifd := int(order.Uint32(tiff[4:]))
+		if ifd+2 > len(tiff) {
+			return 1
+		}
+		entries := int(order.Uint16(tiff[ifd:]))
+		for i := 0; i < entries; i++ {
+			entry := ifd + 2 + i*12
+			if entry+12 > len(tiff) {
+				return 1
+			}
+			if order.Uint16(tiff[entry:]) == 0x0112 {
+				o := int(order.Uint16(tiff[entry+8:]))
+				if o < 1 || o > 8 {
+					return 1
+				}
+				return o
+			}
+		}
+		return 1
+	}
+	return 1
+}
+
+// orient transforms an image according to its EXIF orientation so that it
+// displays upright without the orientation tag
+func orient(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Flip horizontally
+				dx, dy = w-1-x, y
+			case 3: // Rotate 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // Flip vertically
+				dx, dy = x, h-1-y
+			case 5: // Transpose
+				dx, dy = y, x
+			case 6: // Rotate clockwise
+				dx, dy = h-1-y, x
+			case 7: // Transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // Rotate counterclockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,97 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+// testJPEG encodes a blank JPEG of the given size
+func testJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	err := jpeg.Encode(buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// exifSegment builds an APP1 segment holding a big-endian EXIF block with a
+// single orientation entry
+func exifSegment(orientation int) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// withSegment inserts a segment right after the start of image marker
+func withSegment(data []byte, segment []byte) []byte {
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	valid := testJPEG(t, 2, 1)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no EXIF", valid, 1},
+		{"rotated", withSegment(valid, exifSegment(6)), 6},
+		{"mirrored", withSegment(valid, exifSegment(2)), 2},
+		{"out of range", withSegment(valid, exifSegment(9)), 1},
+		{"not a JPEG", []byte("GIF89a"), 1},
+		{"zero segment length", withSegment(valid, []byte{0xFF, 0xD0, 0x00, 0x00}), 1},
+		{"one byte segment length", withSegment(valid, []byte{0xFF, 0xE1, 0x00, 0x01}), 1},
+		{"segment past the end", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x10, 0x00, 'E', 'x'}, 1},
+	}
+	for _, tt := range tests {
+		if got := jpegOrientation(tt.data); got != tt.want {
+			t.Errorf("%s: jpegOrientation() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProcessOrientsJPEG(t *testing.T) {
+	p, err := Process(withSegment(testJPEG(t, 4, 2), exifSegment(6)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Width != 2 || p.Height != 4 {
+		t.Errorf("got %dx%d, want 2x4", p.Width, p.Height)
+	}
+}
+
+// A restart marker, which the decoder skips without reading a length, used to
+// be read as a segment of length 0 and panic while looking for the
+// orientation of an image that decodes fine
+func TestProcessBadSegmentLength(t *testing.T) {
+	data := withSegment(testJPEG(t, 4, 2), []byte{0xFF, 0xD0, 0x00, 0x00})
+	_, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("decoder rejects the image:", err)
+	}
+
+	p, err := Process(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Width != 4 || p.Height != 2 {
+		t.Errorf("got %dx%d, want 4x2", p.Width, p.Height)
+	}
+}
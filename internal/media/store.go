@@ -0,0 +1,70 @@
+// Package media has the storage and processing of uploaded images (chirp
+// attachments and avatars). Blobs are stored through the BlobStore interface
+// so that the storage backend can be swapped out.
+package media
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BlobStore stores blobs under slash-separated keys and knows the public URL
+// each blob is served at
+type BlobStore interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+	URL(key string) string
+}
+
+// LocalStore is a BlobStore keeping blobs as files under Dir, which is
+// expected to be served at BaseURL
+type LocalStore struct {
+	Dir     string
+	BaseURL string
+}
+
+// NewLocalStore creates a local store, making sure its directory exists
+func NewLocalStore(dir string, baseURL string) (*LocalStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalStore{Dir: dir, BaseURL: strings.TrimSuffix(baseURL, "/") + "/"}, nil
+}
+
+// Put writes a blob to disk, creating any intermediate directories
+func (ls *LocalStore) Put(key string, data []byte) error {
+	path := ls.path(key)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Get reads a blob from disk
+func (ls *LocalStore) Get(key string) ([]byte, error) {
+	return os.ReadFile(ls.path(key))
+}
+
+// Delete removes a blob from disk. Deleting a missing blob is not an error.
+func (ls *LocalStore) Delete(key string) error {
+	err := os.Remove(ls.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// URL returns the URL a blob is served at
+func (ls *LocalStore) URL(key string) string {
+	return ls.BaseURL + key
+}
+
+// path maps a key to a file path. Cleaning the key as an absolute path first
+// keeps keys from escaping the store's directory.
+func (ls *LocalStore) path(key string) string {
+	return filepath.Join(ls.Dir, filepath.FromSlash(filepath.Clean("/"+key)))
+}
@@ -0,0 +1,141 @@
+// Package ratelimit implements token bucket rate limiting. Every key (a user
+// or a client IP) gets its own bucket holding up to a policy's limit of
+// tokens, which refill steadily over the policy's window. Each request takes
+// a token and is rejected when the bucket is empty, so short bursts are
+// allowed while the long-run rate stays within the limit.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidPolicy is returned for policies that aren't of the form
+// "limit/window" with a positive limit and window
+var ErrInvalidPolicy = errors.New("invalid rate limit policy")
+
+// Policy allows Limit requests per Window
+type Policy struct {
+	Limit  int
+	Window time.Duration
+}
+
+// ParsePolicy parses a policy of the form "limit/window", e.g. "5/1m"
+func ParsePolicy(s string) (Policy, error) {
+	limitStr, windowStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return Policy{}, ErrInvalidPolicy
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+	if err != nil || limit < 1 {
+		return Policy{}, ErrInvalidPolicy
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(windowStr))
+	if err != nil || window <= 0 {
+		return Policy{}, ErrInvalidPolicy
+	}
+	return Policy{Limit: limit, Window: window}, nil
+}
+
+// String formats the policy the way the RateLimit-Policy header expects it,
+// e.g. "5;w=60"
+func (p Policy) String() string {
+	return fmt.Sprintf("%d;w=%d", p.Limit, int(math.Ceil(p.Window.Seconds())))
+}
+
+// Result is the outcome of taking a token. Reset is how long until the bucket
+// is full again, and RetryAfter how long until the next token if the request
+// wasn't allowed.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter holds the buckets of a single policy. It is safe for concurrent
+// use.
+type Limiter struct {
+	mux       sync.Mutex
+	policy    Policy
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New creates a limiter enforcing the given policy
+func New(policy Policy) *Limiter {
+	return &Limiter{
+		policy:    policy,
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Policy returns the policy the limiter enforces
+func (l *Limiter) Policy() Policy {
+	return l.policy
+}
+
+// Allow takes a token from the key's bucket. The policy's limit is multiplied
+// by scale for the key, which lets some keys make more requests than others
+// in the same window.
+func (l *Limiter) Allow(key string, scale int) Result {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	now := l.now()
+	capacity := float64(l.policy.Limit * max(scale, 1))
+	rate := capacity / l.policy.Window.Seconds()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	res := Result{Limit: int(capacity)}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((capacity - b.tokens) / rate)
+
+	l.sweep(now)
+	return res
+}
+
+// sweep drops the buckets that have refilled completely, as they're no
+// different from new ones. It runs at most once per window.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.policy.Window {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.policy.Window {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
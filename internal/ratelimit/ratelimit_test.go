@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Policy
+		wantErr error
+	}{
+		{"5/1m", Policy{Limit: 5, Window: time.Minute}, nil},
+		{" 120 / 30s ", Policy{Limit: 120, Window: 30 * time.Second}, nil},
+		{"1/1h", Policy{Limit: 1, Window: time.Hour}, nil},
+		{"5", Policy{}, ErrInvalidPolicy},
+		{"0/1m", Policy{}, ErrInvalidPolicy},
+		{"-1/1m", Policy{}, ErrInvalidPolicy},
+		{"x/1m", Policy{}, ErrInvalidPolicy},
+		{"5/0s", Policy{}, ErrInvalidPolicy},
+		{"5/-1m", Policy{}, ErrInvalidPolicy},
+		{"5/minute", Policy{}, ErrInvalidPolicy},
+	}
+	for _, tt := range tests {
+		got, err := ParsePolicy(tt.in)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("ParsePolicy(%q) = %+v, %v, want %+v, %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPolicyString(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		want   string
+	}{
+		{Policy{Limit: 5, Window: time.Minute}, "5;w=60"},
+		{Policy{Limit: 10, Window: 1500 * time.Millisecond}, "10;w=2"},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.policy, got, tt.want)
+		}
+	}
+}
+
+// testLimiter returns a limiter whose clock only moves when advanced
+func testLimiter(policy Policy) (*Limiter, func(time.Duration)) {
+	l := New(policy)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.lastSweep = now
+	l.now = func() time.Time { return now }
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestAllow(t *testing.T) {
+	type step struct {
+		advance       time.Duration
+		key           string
+		scale         int
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"burst up to the limit", []step{
+			{0, "a", 1, true, 2, 0},
+			{0, "a", 1, true, 1, 0},
+			{0, "a", 1, true, 0, 0},
+			{0, "a", 1, false, 0, 20 * time.Second},
+		}},
+		{"refill over the window", []step{
+			{0, "a", 1, true, 2, 0},
+			{0, "a", 1, true, 1, 0},
+			{0, "a", 1, true, 0, 0},
+			{10 * time.Second, "a", 1, false, 0, 10 * time.Second},
+			{10 * time.Second, "a", 1, true, 0, 0},
+			{time.Hour, "a", 1, true, 2, 0},
+		}},
+		{"separate keys", []step{
+			{0, "a", 1, true, 2, 0},
+			{0, "a", 1, true, 1, 0},
+			{0, "a", 1, true, 0, 0},
+			{0, "b", 1, true, 2, 0},
+		}},
+		{"scaled limit", []step{
+			{0, "a", 2, true, 5, 0},
+			{0, "a", 2, true, 4, 0},
+			{0, "a", 2, true, 3, 0},
+			{0, "a", 2, true, 2, 0},
+			{0, "a", 2, true, 1, 0},
+			{0, "a", 2, true, 0, 0},
+			{0, "a", 2, false, 0, 10 * time.Second},
+		}},
+		{"scale below one", []step{
+			{0, "a", 0, true, 2, 0},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, advance := testLimiter(Policy{Limit: 3, Window: time.Minute})
+			for i, s := range tt.steps {
+				advance(s.advance)
+				res := l.Allow(s.key, s.scale)
+				if res.Allowed != s.wantAllowed || res.Remaining != s.wantRemaining || res.RetryAfter != s.wantRetry {
+					t.Errorf("step %d: Allow(%q, %d) = %+v, want allowed %v, remaining %d, retry after %v",
+						i, s.key, s.scale, res, s.wantAllowed, s.wantRemaining, s.wantRetry)
+				}
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	l, _ := testLimiter(Policy{Limit: 3, Window: time.Minute})
+	res := l.Allow("a", 1)
+	if res.Limit != 3 || res.Reset != 20*time.Second {
+		t.Errorf("Allow = %+v, want limit 3 and reset 20s", res)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	l, advance := testLimiter(Policy{Limit: 3, Window: time.Minute})
+	l.Allow("a", 1)
+	advance(30 * time.Second)
+	l.Allow("b", 1)
+	if len(l.buckets) != 2 {
+		t.Fatalf("buckets swept before a window passed: %d left", len(l.buckets))
+	}
+
+	advance(45 * time.Second)
+	l.Allow("c", 1)
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("refilled bucket wasn't swept")
+	}
+	if _, ok := l.buckets["b"]; !ok {
+		t.Error("bucket still refilling was swept")
+	}
+}
@@ -0,0 +1,28 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/wipdev-tech/chirpy/internal/text"
+)
+
+// parseQuery splits a query into phrases. Quoted parts of the query are kept
+// together as a phrase, while every other token is a phrase of its own.
+func parseQuery(query string) [][]string {
+	phrases := [][]string{}
+	for i, part := range strings.Split(query, `"`) {
+		tokens := text.Tokenize(part)
+		if len(tokens) == 0 {
+			continue
+		}
+		// Odd parts are the ones between quotes
+		if i%2 == 1 {
+			phrases = append(phrases, tokens)
+			continue
+		}
+		for _, t := range tokens {
+			phrases = append(phrases, []string{t})
+		}
+	}
+	return phrases
+}
@@ -0,0 +1,229 @@
+// Package search has an in-process inverted index over chirps and users. The
+// index lives in memory only; it is rebuilt from the database at startup and
+// kept up to date as chirps and users are created, updated and deleted.
+package search
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/text"
+)
+
+// Query holds the parameters of a chirp search. Zero values mean no filter.
+type Query struct {
+	Text       string
+	AuthorID   int
+	Since      time.Time
+	Until      time.Time
+	SortRecent bool
+}
+
+// chirpMeta holds the chirp data needed for filtering and recency ordering
+type chirpMeta struct {
+	authorID  int
+	createdAt time.Time
+}
+
+// docSet is an inverted index over one type of document. Postings map a token
+// to the documents containing it and the positions it appears at.
+type docSet struct {
+	postings map[string]map[int][]int
+	tokens   map[int][]string
+}
+
+func newDocSet() docSet {
+	return docSet{
+		postings: map[string]map[int][]int{},
+		tokens:   map[int][]string{},
+	}
+}
+
+func (d docSet) add(id int, body string) {
+	d.remove(id)
+
+	tokens := text.Tokenize(body)
+	d.tokens[id] = tokens
+	for pos, t := range tokens {
+		if d.postings[t] == nil {
+			d.postings[t] = map[int][]int{}
+		}
+		d.postings[t][id] = append(d.postings[t][id], pos)
+	}
+}
+
+func (d docSet) remove(id int) {
+	for _, t := range d.tokens[id] {
+		delete(d.postings[t], id)
+		if len(d.postings[t]) == 0 {
+			delete(d.postings, t)
+		}
+	}
+	delete(d.tokens, id)
+}
+
+// match returns the documents containing every phrase along with their TF-IDF
+// relevance scores
+func (d docSet) match(phrases [][]string) map[int]float64 {
+	scores := map[int]float64{}
+	if len(phrases) == 0 {
+		return scores
+	}
+
+	for i, phrase := range phrases {
+		phraseScores := map[int]float64{}
+		for id, positions := range d.postings[phrase[0]] {
+			count := 0
+			for _, pos := range positions {
+				if d.hasPhraseAt(id, phrase, pos) {
+					count++
+				}
+			}
+			if count == 0 {
+				continue
+			}
+
+			idf := 0.0
+			for _, t := range phrase {
+				idf += math.Log(1 + float64(len(d.tokens))/float64(len(d.postings[t])))
+			}
+			phraseScores[id] = float64(count) / float64(len(d.tokens[id])) * idf
+		}
+
+		if i == 0 {
+			scores = phraseScores
+			continue
+		}
+		for id := range scores {
+			if score, ok := phraseScores[id]; ok {
+				scores[id] += score
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	return scores
+}
+
+func (d docSet) hasPhraseAt(id int, phrase []string, pos int) bool {
+	tokens := d.tokens[id]
+	if pos+len(phrase) > len(tokens) {
+		return false
+	}
+	return slices.Equal(tokens[pos:pos+len(phrase)], phrase)
+}
+
+// Index is the search index over chirps and users. It is safe for concurrent
+// use.
+type Index struct {
+	mux       *sync.RWMutex
+	chirps    docSet
+	chirpMeta map[int]chirpMeta
+	users     docSet
+}
+
+// NewIndex creates an empty search index
+func NewIndex() *Index {
+	return &Index{
+		mux:       &sync.RWMutex{},
+		chirps:    newDocSet(),
+		chirpMeta: map[int]chirpMeta{},
+		users:     newDocSet(),
+	}
+}
+
+// AddChirp indexes (or reindexes) a chirp body
+func (ix *Index) AddChirp(id int, authorID int, body string, createdAt time.Time) {
+	ix.mux.Lock()
+	defer ix.mux.Unlock()
+
+	ix.chirps.add(id, body)
+	ix.chirpMeta[id] = chirpMeta{authorID: authorID, createdAt: createdAt}
+}
+
+// RemoveChirp removes a chirp from the index
+func (ix *Index) RemoveChirp(id int) {
+	ix.mux.Lock()
+	defer ix.mux.Unlock()
+
+	ix.chirps.remove(id)
+	delete(ix.chirpMeta, id)
+}
+
+// AddUser indexes (or reindexes) the searchable text of a user
+func (ix *Index) AddUser(id int, text string) {
+	ix.mux.Lock()
+	defer ix.mux.Unlock()
+
+	ix.users.add(id, text)
+}
+
+// RemoveUser removes a user from the index
+func (ix *Index) RemoveUser(id int) {
+	ix.mux.Lock()
+	defer ix.mux.Unlock()
+
+	ix.users.remove(id)
+}
+
+// SearchChirps returns the IDs of the chirps matching the query, ordered by
+// relevance or, if requested, by recency
+func (ix *Index) SearchChirps(q Query) []int {
+	ix.mux.RLock()
+	defer ix.mux.RUnlock()
+
+	scores := ix.chirps.match(parseQuery(q.Text))
+	ids := []int{}
+	for id := range scores {
+		meta := ix.chirpMeta[id]
+		if q.AuthorID != 0 && meta.authorID != q.AuthorID {
+			continue
+		}
+		if !q.Since.IsZero() && meta.createdAt.Before(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && meta.createdAt.After(q.Until) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	slices.SortFunc(ids, func(a, b int) int {
+		if !q.SortRecent && scores[a] != scores[b] {
+			if scores[a] > scores[b] {
+				return -1
+			}
+			return 1
+		}
+		// Newer chirps first, with higher IDs being newer
+		return b - a
+	})
+	return ids
+}
+
+// SearchUsers returns the IDs of the users matching the query text, ordered by
+// relevance
+func (ix *Index) SearchUsers(text string) []int {
+	ix.mux.RLock()
+	defer ix.mux.RUnlock()
+
+	scores := ix.users.match(parseQuery(text))
+	ids := []int{}
+	for id := range scores {
+		ids = append(ids, id)
+	}
+
+	slices.SortFunc(ids, func(a, b int) int {
+		if scores[a] != scores[b] {
+			if scores[a] > scores[b] {
+				return -1
+			}
+			return 1
+		}
+		return a - b
+	})
+	return ids
+}
@@ -0,0 +1,117 @@
+package search
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  [][]string
+	}{
+		{"", [][]string{}},
+		{"go", [][]string{{"go"}}},
+		{"Go Chirpy!", [][]string{{"go"}, {"chirpy"}}},
+		{`"hello world" go`, [][]string{{"hello", "world"}, {"go"}}},
+		{`go "hello world"`, [][]string{{"go"}, {"hello", "world"}}},
+		{`"unterminated phrase`, [][]string{{"unterminated", "phrase"}}},
+		{`"" go`, [][]string{{"go"}}},
+		{"#café", [][]string{{"cafe"}}},
+	}
+	for _, tt := range tests {
+		got := parseQuery(tt.query)
+		if !slices.EqualFunc(got, tt.want, slices.Equal[[]string]) {
+			t.Errorf("parseQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func newTestIndex() *Index {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ix := NewIndex()
+	ix.AddChirp(1, 1, "Learning Go today", base)
+	ix.AddChirp(2, 2, "go go go, Go is great", base.Add(time.Hour))
+	ix.AddChirp(3, 1, "Hello world from the café", base.Add(2*time.Hour))
+	ix.AddChirp(4, 2, "world hello, said nobody", base.Add(3*time.Hour))
+	ix.AddChirp(5, 3, "Nothing relevant here", base.Add(4*time.Hour))
+	return ix
+}
+
+func TestSearchChirps(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		query Query
+		want  []int
+	}{
+		{"empty query", Query{}, []int{}},
+		{"no match", Query{Text: "rust"}, []int{}},
+		{"ranked by relevance", Query{Text: "go"}, []int{2, 1}},
+		{"sorted by recency", Query{Text: "go", SortRecent: true}, []int{2, 1}},
+		{"all terms required", Query{Text: "hello café"}, []int{3}},
+		{"folded", Query{Text: "CAFE"}, []int{3}},
+		{"phrase", Query{Text: `"hello world"`}, []int{3}},
+		{"terms in any order", Query{Text: "hello world", SortRecent: true}, []int{4, 3}},
+		{"author", Query{Text: "hello", AuthorID: 2}, []int{4}},
+		{"since", Query{Text: "hello", Since: base.Add(150 * time.Minute)}, []int{4}},
+		{"until", Query{Text: "go", Until: base.Add(30 * time.Minute)}, []int{1}},
+	}
+	ix := newTestIndex()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ix.SearchChirps(tt.query)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SearchChirps(%+v) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReindexAndRemove(t *testing.T) {
+	ix := newTestIndex()
+
+	ix.AddChirp(1, 1, "Learning Rust today", time.Now())
+	if got := ix.SearchChirps(Query{Text: "go"}); !slices.Equal(got, []int{2}) {
+		t.Errorf("after reindexing, search for the old body = %v, want [2]", got)
+	}
+	if got := ix.SearchChirps(Query{Text: "rust"}); !slices.Equal(got, []int{1}) {
+		t.Errorf("after reindexing, search for the new body = %v, want [1]", got)
+	}
+
+	ix.RemoveChirp(2)
+	if got := ix.SearchChirps(Query{Text: "go"}); len(got) != 0 {
+		t.Errorf("after removal, search = %v, want none", got)
+	}
+	if _, ok := ix.chirps.postings["great"]; ok {
+		t.Error("postings of a removed chirp were kept")
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	ix := NewIndex()
+	ix.AddUser(1, "alice Alice Liddell")
+	ix.AddUser(2, "bob Bob Alice-Fan")
+	ix.AddUser(3, "zoë")
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"alice", []int{1, 2}},
+		{"bob", []int{2}},
+		{"zoe", []int{3}},
+		{"carol", []int{}},
+	}
+	for _, tt := range tests {
+		if got := ix.SearchUsers(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("SearchUsers(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	ix.RemoveUser(1)
+	if got := ix.SearchUsers("alice"); !slices.Equal(got, []int{2}) {
+		t.Errorf("after removal, SearchUsers = %v, want [2]", got)
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// exportedAccount is the account part of a data export. The password hash
+// isn't exported, and neither are shadow bans so they aren't given away.
+type exportedAccount struct {
+	ID                 int        `json:"id"`
+	Email              string     `json:"email"`
+	Handle             string     `json:"handle"`
+	DisplayName        string     `json:"display_name"`
+	Bio                string     `json:"bio"`
+	AvatarURL          string     `json:"avatar_url"`
+	IsChirpyRed        bool       `json:"is_chirpy_red"`
+	Role               string     `json:"role"`
+	Protected          bool       `json:"protected"`
+	OpenDMs            bool       `json:"open_dms"`
+	MutedNotifications []string   `json:"muted_notifications"`
+	Suspended          bool       `json:"suspended"`
+	SuspendedUntil     *time.Time `json:"suspended_until,omitempty"`
+	SuspensionReason   string     `json:"suspension_reason,omitempty"`
+	CreatedAt          *time.Time `json:"created_at,omitempty"`
+}
+
+const (
+	minPasswordLength = 8
+	// maxPasswordBytes is the most bcrypt will hash
+	maxPasswordBytes = 72
+)
+
+// validateEmail returns the trimmed email if it's a bare address
+func validateEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
+}
+
+// validatePassword checks the password against the password policy: at least
+// 8 characters but no more than 72 bytes, with both letters and something
+// other than letters, and not the same as any of the given emails.
+func validatePassword(password string, emails ...string) error {
+	if utf8.RuneCountInString(password) < minPasswordLength || len(password) > maxPasswordBytes {
+		return ErrWeakPassword
+	}
+	letters, others := 0, 0
+	for _, r := range password {
+		if unicode.IsLetter(r) {
+			letters++
+		} else {
+			others++
+		}
+	}
+	if letters == 0 || others == 0 {
+		return ErrWeakPassword
+	}
+	for _, email := range emails {
+		if email != "" && strings.EqualFold(password, email) {
+			return ErrWeakPassword
+		}
+	}
+	return nil
+}
+
+// avatarKeys returns the keys the user's avatar may be stored under
+func avatarKeys(userID int) []string {
+	return []string{fmt.Sprintf("avatars/%d.jpg", userID), fmt.Sprintf("avatars/%d.png", userID)}
+}
+
+// DeleteUser deletes the user's account once they confirm their password.
+// Their chirps, likes, follows and everything else tied to the account are
+// deleted with it, and since the account's ID is never reused, all of its
+// tokens stop working and its streams are closed. The deletion is audited
+// along with the IP it came from.
+func (s *Service) DeleteUser(userID int, password string, ip string) error {
+	u, err := s.dbConn.GetUser(userID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) != nil {
+		return ErrWrongPassword
+	}
+
+	chirps, mediaList, err := s.dbConn.DeleteUser(userID)
+	if err != nil {
+		return err
+	}
+	s.record(AuditUserDeleted, userID, ip, userTarget(userID), nil)
+	s.hub.Disconnect(userID)
+
+	s.deleteChirpBlobs(mediaList)
+	for _, key := range avatarKeys(userID) {
+		err = s.blobs.Delete(key)
+		if err != nil {
+			fmt.Println("Error deleting blob:", err)
+		}
+	}
+	for _, c := range chirps {
+		s.trends.Remove(c.ID)
+		s.index.RemoveChirp(c.ID)
+	}
+	s.index.RemoveUser(userID)
+	return nil
+}
+
+// ExportUser returns a zip archive of everything stored about the user: their
+// account, one JSON file per kind of data and the files of their avatar and
+// uploaded media. The export is audited along with the IP it came from.
+func (s *Service) ExportUser(userID int, ip string) ([]byte, error) {
+	data, err := s.dbConn.GetUserData(userID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	u := data.User
+	account := exportedAccount{
+		ID:                 u.ID,
+		Email:              u.Email,
+		Handle:             u.Handle,
+		DisplayName:        u.DisplayName,
+		Bio:                u.Bio,
+		AvatarURL:          u.AvatarURL,
+		IsChirpyRed:        u.IsChirpyRed,
+		Role:               roleOf(u),
+		Protected:          u.Protected,
+		OpenDMs:            u.OpenDMs,
+		MutedNotifications: u.MutedNotifications,
+		Suspended:          isSuspended(u),
+		SuspendedUntil:     u.SuspendedUntil,
+		SuspensionReason:   u.SuspensionReason,
+	}
+	if !u.CreatedAt.IsZero() {
+		account.CreatedAt = &u.CreatedAt
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range []struct {
+		name    string
+		content any
+	}{
+		{"account.json", account},
+		{"chirps.json", data.Chirps},
+		{"chirp_versions.json", data.ChirpVersions},
+		{"drafts.json", data.Drafts},
+		{"media.json", data.Media},
+		{"likes.json", data.Likes},
+		{"bookmarks.json", data.Bookmarks},
+		{"votes.json", data.Votes},
+		{"follows.json", data.Follows},
+		{"follow_requests.json", data.FollowRequests},
+		{"blocks.json", data.Blocks},
+		{"mutes.json", data.Mutes},
+		{"lists.json", data.Lists},
+		{"notifications.json", data.Notifications},
+		{"conversations.json", data.Conversations},
+		{"messages.json", data.Messages},
+		{"reports.json", data.Reports},
+	} {
+		content, err := json.MarshalIndent(file.content, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		err = addToArchive(archive, file.name, content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	keys := avatarKeys(userID)
+	for _, m := range data.Media {
+		keys = append(keys, m.Key, m.ThumbnailKey)
+	}
+	for _, key := range keys {
+		content, err := s.blobs.Get(key)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		err = addToArchive(archive, key, content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return nil, err
+	}
+	s.record(AuditDataExported, userID, ip, userTarget(userID), nil)
+	return buf.Bytes(), nil
+}
+
+func addToArchive(archive *zip.Writer, name string, content []byte) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
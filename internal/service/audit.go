@@ -0,0 +1,119 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/audit"
+	"github.com/wipdev-tech/chirpy/internal/db"
+)
+
+// Audited events
+const (
+	AuditLogin             = "login"
+	AuditLoginFailed       = "login_failed"
+	AuditUserUpdated       = "user_updated"
+	AuditTokenRevoked      = "token_revoked"
+	AuditChirpyRedUpgrade  = "chirpy_red_upgrade"
+	AuditChirpDeleted      = "chirp_deleted"
+	AuditModeration        = "moderation"
+	AuditRoleChanged       = "role_changed"
+	AuditFilterRuleSet     = "filter_rule_set"
+	AuditFilterRuleDeleted = "filter_rule_deleted"
+	AuditSpamReview        = "spam_review"
+	AuditUserDeleted       = "user_deleted"
+	AuditDataExported      = "data_exported"
+)
+
+// ResAuditVerification holds the result of checking the audit log's hash
+// chain. BrokenAt is the sequence number of the first entry that doesn't
+// check out.
+type ResAuditVerification struct {
+	Valid    bool `json:"valid"`
+	BrokenAt int  `json:"broken_at,omitempty"`
+}
+
+// InitAudit opens the audit log at AUDIT_LOG, or audit.log if that isn't set
+func (s *Service) InitAudit() {
+	path := os.Getenv("AUDIT_LOG")
+	if path == "" {
+		path = "audit.log"
+	}
+
+	log, err := audit.Open(path)
+	if err != nil {
+		panic(err)
+	}
+	s.audit = log
+}
+
+// record appends an event to the audit log. The action being audited has
+// already happened by the time it's recorded, so errors are only logged.
+func (s *Service) record(event string, actorID int, ip string, target string, details map[string]string) {
+	_, err := s.audit.Append(audit.Entry{
+		Event:   event,
+		ActorID: actorID,
+		IP:      ip,
+		Target:  target,
+		Details: details,
+	})
+	if err != nil {
+		fmt.Println("Error writing audit log:", err)
+	}
+}
+
+// recordModAction audits a moderation action
+func (s *Service) recordModAction(a db.ModAction, ip string) {
+	details := map[string]string{"action": a.Action}
+	if a.ReportID != 0 {
+		details["report_id"] = fmt.Sprint(a.ReportID)
+	}
+	if a.ChirpID != 0 {
+		details["chirp_id"] = fmt.Sprint(a.ChirpID)
+	}
+	if a.Note != "" {
+		details["note"] = a.Note
+	}
+	if a.Until != nil {
+		details["until"] = a.Until.Format(time.RFC3339)
+	}
+	s.record(AuditModeration, a.ModeratorID, ip, userTarget(a.UserID), details)
+}
+
+func userTarget(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+func chirpTarget(chirpID int) string {
+	return fmt.Sprintf("chirp:%d", chirpID)
+}
+
+// GetAuditLog returns the audit log entries matching the filter, newest
+// first, to an admin
+func (s *Service) GetAuditLog(adminID int, f audit.Filter) ([]audit.Entry, error) {
+	err := s.RequireRole(adminID, db.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return s.audit.Query(f)
+}
+
+// VerifyAuditLog checks that no audit log entry has been changed or removed
+// on behalf of an admin
+func (s *Service) VerifyAuditLog(adminID int) (ResAuditVerification, error) {
+	err := s.RequireRole(adminID, db.RoleAdmin)
+	if err != nil {
+		return ResAuditVerification{}, err
+	}
+
+	brokenAt, err := s.audit.Verify()
+	if errors.Is(err, audit.ErrTampered) {
+		return ResAuditVerification{BrokenAt: brokenAt}, nil
+	}
+	if err != nil {
+		return ResAuditVerification{}, err
+	}
+	return ResAuditVerification{Valid: true}, nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"slices"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+)
+
+// relationsOf returns the users hidden from the given user: blocked holds the
+// users the user blocked or was blocked by, and muted holds the users the
+// user muted. Anonymous users (ID 0) have no relations.
+func (s *Service) relationsOf(userID int) (blocked map[int]bool, muted map[int]bool, err error) {
+	blocked, muted = map[int]bool{}, map[int]bool{}
+	if userID == 0 {
+		return blocked, muted, nil
+	}
+
+	blocks, err := s.dbConn.GetBlocks()
+	if err != nil {
+		return nil, nil, err
+	}
+	mutes, err := s.dbConn.GetMutes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blocked, muted = relationsIn(blocks, mutes, userID)
+	return blocked, muted, nil
+}
+
+// relationsIn is relationsOf over blocks and mutes that are already loaded
+func relationsIn(blocks []db.Block, mutes []db.Mute, userID int) (blocked map[int]bool, muted map[int]bool) {
+	blocked, muted = map[int]bool{}, map[int]bool{}
+	for _, b := range blocks {
+		if b.BlockerID == userID {
+			blocked[b.BlockedID] = true
+		}
+		if b.BlockedID == userID {
+			blocked[b.BlockerID] = true
+		}
+	}
+	for _, m := range mutes {
+		if m.MuterID == userID {
+			muted[m.MutedID] = true
+		}
+	}
+	return blocked, muted
+}
+
+// isBlocked reports whether either user blocked the other
+func (s *Service) isBlocked(userID int, otherID int) (bool, error) {
+	blocked, _, err := s.relationsOf(userID)
+	return blocked[otherID], err
+}
+
+// Block makes the blocker block the user with the given ID. Blocking removes
+// follows in both directions.
+func (s *Service) Block(blockerID int, blockedID int) error {
+	if blockerID == blockedID {
+		return ErrSelfBlock
+	}
+
+	users, err := s.dbConn.GetUsers()
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(users, func(u db.User) bool { return u.ID == blockedID }) {
+		return ErrUserNotFound
+	}
+
+	return s.dbConn.CreateBlock(blockerID, blockedID)
+}
+
+// Unblock makes the blocker unblock the user with the given ID
+func (s *Service) Unblock(blockerID int, blockedID int) error {
+	return s.dbConn.DeleteBlock(blockerID, blockedID)
+}
+
+// Mute makes the muter mute the user with the given ID
+func (s *Service) Mute(muterID int, mutedID int) error {
+	if muterID == mutedID {
+		return ErrSelfBlock
+	}
+
+	users, err := s.dbConn.GetUsers()
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(users, func(u db.User) bool { return u.ID == mutedID }) {
+		return ErrUserNotFound
+	}
+
+	return s.dbConn.CreateMute(muterID, mutedID)
+}
+
+// Unmute makes the muter unmute the user with the given ID
+func (s *Service) Unmute(muterID int, mutedID int) error {
+	return s.dbConn.DeleteMute(muterID, mutedID)
+}
+
+// GetBlockedUsers returns the users blocked by the given user
+func (s *Service) GetBlockedUsers(userID int) ([]ResAuthor, error) {
+	blocks, err := s.dbConn.GetBlocks()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int{}
+	for _, b := range blocks {
+		if b.BlockerID == userID {
+			ids = append(ids, b.BlockedID)
+		}
+	}
+	return s.authorsOf(ids)
+}
+
+// GetMutedUsers returns the users muted by the given user
+func (s *Service) GetMutedUsers(userID int) ([]ResAuthor, error) {
+	mutes, err := s.dbConn.GetMutes()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int{}
+	for _, m := range mutes {
+		if m.MuterID == userID {
+			ids = append(ids, m.MutedID)
+		}
+	}
+	return s.authorsOf(ids)
+}
+
+// authorsOf returns the public data of the users with the given IDs
+func (s *Service) authorsOf(ids []int) ([]ResAuthor, error) {
+	authors := []ResAuthor{}
+
+	users, err := s.dbConn.GetUsers()
+	if err != nil {
+		return authors, err
+	}
+
+	for _, u := range users {
+		if slices.Contains(ids, u.ID) {
+			authors = append(authors, toResAuthor(u))
+		}
+	}
+	slices.SortFunc(authors, func(a, b ResAuthor) int { return a.ID - b.ID })
+	return authors, nil
+}
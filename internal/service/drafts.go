@@ -0,0 +1,214 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+)
+
+// defaultSchedulerInterval is how often the scheduler looks for due chirps
+const defaultSchedulerInterval = 10 * time.Second
+
+// SaveDraft saves a chirp for later. Without a publish time it is kept as a
+// draft, otherwise it is scheduled to be published at that time. The body is
+// checked the same way as a new chirp's so problems surface right away.
+func (s *Service) SaveDraft(authorID int, body string, mediaIDs []int, visibility string, publishAt *time.Time) (db.Draft, error) {
+	return s.saveDraft(db.Draft{AuthorID: authorID}, body, mediaIDs, visibility, publishAt)
+}
+
+// UpdateDraft replaces the body, media, visibility and publish time of one of
+// the user's drafts. Failed drafts can be rescheduled this way.
+func (s *Service) UpdateDraft(userID int, draftID int, body string, mediaIDs []int, visibility string, publishAt *time.Time) (db.Draft, error) {
+	d, err := s.draftOf(userID, draftID)
+	if err != nil {
+		return d, err
+	}
+	return s.saveDraft(d, body, mediaIDs, visibility, publishAt)
+}
+
+// saveDraft validates and saves a new or existing draft
+func (s *Service) saveDraft(d db.Draft, body string, mediaIDs []int, visibility string, publishAt *time.Time) (db.Draft, error) {
+	if len(mediaIDs) > maxMediaPerChirp {
+		return d, ErrInvalidMedia
+	}
+	if publishAt != nil && !publishAt.After(time.Now()) {
+		return d, ErrInvalidPublishTime
+	}
+
+	visibility, err := validateVisibility(visibility)
+	if err != nil {
+		return d, err
+	}
+
+	err = s.checkLength(d.AuthorID, body)
+	if err != nil {
+		return d, err
+	}
+	_, _, err = s.filterBody(body)
+	if err != nil {
+		return d, err
+	}
+
+	d.Body = body
+	d.MediaIDs = mediaIDs
+	d.Visibility = visibility
+	d.PublishAt = publishAt
+	d.Error = ""
+	d.Status = db.DraftSaved
+	if publishAt != nil {
+		d.Status = db.DraftScheduled
+	}
+	return s.dbConn.SaveDraft(d)
+}
+
+// draftOf returns the unpublished draft of the given ID if it belongs to the
+// user
+func (s *Service) draftOf(userID int, draftID int) (db.Draft, error) {
+	drafts, err := s.dbConn.GetDrafts()
+	if err != nil {
+		return db.Draft{}, err
+	}
+
+	i := slices.IndexFunc(drafts, func(d db.Draft) bool { return d.ID == draftID })
+	if i == -1 || drafts[i].AuthorID != userID || drafts[i].Status == db.DraftPublished {
+		return db.Draft{}, ErrDraftNotFound
+	}
+	return drafts[i], nil
+}
+
+// GetDrafts returns the user's unpublished drafts, scheduled ones first in
+// the order they're due, then the rest with the most recently updated first
+func (s *Service) GetDrafts(userID int) ([]db.Draft, error) {
+	drafts, err := s.dbConn.GetDrafts()
+	if err != nil {
+		return drafts, err
+	}
+
+	drafts = slices.DeleteFunc(drafts, func(d db.Draft) bool {
+		return d.AuthorID != userID || d.Status == db.DraftPublished
+	})
+	slices.SortFunc(drafts, func(a, b db.Draft) int {
+		aScheduled, bScheduled := a.Status == db.DraftScheduled, b.Status == db.DraftScheduled
+		switch {
+		case aScheduled && bScheduled:
+			return a.PublishAt.Compare(*b.PublishAt)
+		case aScheduled:
+			return -1
+		case bScheduled:
+			return 1
+		}
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+	return drafts, nil
+}
+
+// DeleteDraft deletes one of the user's drafts, unscheduling it if needed
+func (s *Service) DeleteDraft(userID int, draftID int) error {
+	_, err := s.draftOf(userID, draftID)
+	if err != nil {
+		return err
+	}
+	return s.dbConn.DeleteDraft(draftID)
+}
+
+// PublishDraft publishes one of the user's drafts right away
+func (s *Service) PublishDraft(userID int, draftID int) (ResChirp, error) {
+	d, err := s.draftOf(userID, draftID)
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	chirp, err := s.publishDraft(d)
+	if errors.Is(err, db.ErrDraftChanged) {
+		return ResChirp{}, ErrDraftNotFound
+	}
+	return chirp, err
+}
+
+// publishDraft turns a draft into a chirp. The draft's body is checked again
+// since the filter rules or the author's length limit may have changed, and
+// drafts of suspended authors aren't published.
+func (s *Service) publishDraft(d db.Draft) (ResChirp, error) {
+	author, err := s.dbConn.GetUser(d.AuthorID)
+	if err != nil {
+		return ResChirp{}, err
+	}
+	if isSuspended(author) {
+		return ResChirp{}, ErrSuspended
+	}
+
+	newChirp, checks, err := s.prepareChirp(d.AuthorID, d.Body, d.MediaIDs, d.Visibility)
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	newChirp, err = s.dbConn.PublishDraft(d, newChirp)
+	if errors.Is(err, db.ErrInvalidMedia) {
+		return ResChirp{}, ErrInvalidMedia
+	}
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	return s.chirpPosted(newChirp, checks)
+}
+
+// StartScheduler starts publishing scheduled chirps in the background. Chirps
+// that became due while the server was down are published right away. The
+// interval between checks is read from the SCHEDULER_INTERVAL environment
+// variable (e.g. "1s") and defaults to ten seconds.
+//
+// A draft is marked as published in the same write that saves its chirp, so
+// it is published at most once even if the user publishes it manually at the
+// same time.
+func (s *Service) StartScheduler() {
+	interval := defaultSchedulerInterval
+	if env := os.Getenv("SCHEDULER_INTERVAL"); env != "" {
+		d, err := time.ParseDuration(env)
+		if err != nil {
+			panic(err)
+		}
+		interval = d
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.publishDue()
+			<-ticker.C
+		}
+	}()
+}
+
+// publishDue publishes every scheduled draft whose publish time has passed.
+// Drafts that can't be published are marked as failed so that they aren't
+// retried until the user reschedules them.
+func (s *Service) publishDue() {
+	drafts, err := s.dbConn.GetDrafts()
+	if err != nil {
+		fmt.Println("Error getting drafts:", err)
+		return
+	}
+
+	now := time.Now()
+	for _, d := range drafts {
+		if d.Status != db.DraftScheduled || d.PublishAt.After(now) {
+			continue
+		}
+
+		_, err := s.publishDraft(d)
+		if err == nil || errors.Is(err, db.ErrDraftChanged) {
+			continue
+		}
+		fmt.Printf("Error publishing draft %d: %v\n", d.ID, err)
+		err = s.dbConn.FailDraft(d, err.Error())
+		if err != nil && !errors.Is(err, db.ErrDraftChanged) {
+			fmt.Println("Error marking draft as failed:", err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/stream"
+)
+
+// defaultEditWindow is how long after posting a chirp can be edited
+const defaultEditWindow = 15 * time.Minute
+
+// ResChirpVersion is a version of a chirp's body in its edit history
+type ResChirpVersion struct {
+	Version   int          `json:"version"`
+	Body      string       `json:"body"`
+	Mentions  []db.Mention `json:"mentions"`
+	Hashtags  []db.Hashtag `json:"hashtags"`
+	CreatedAt time.Time    `json:"created_at"`
+	Current   bool         `json:"current"`
+}
+
+// EditChirp replaces the body of one of the user's chirps, keeping the
+// previous body in its history. The new body goes through the same length
+// check, content filter and spam scoring as a new chirp, and users mentioned
+// for the first time are notified. Edits scored as likely spam hold the chirp
+// for review, hiding it from everyone but its author until it's approved.
+// Chirps can only be edited within the edit window and while they aren't held
+// for review, and rechirps have no body to edit.
+func (s *Service) EditChirp(userID int, chirpID string, body string) (ResChirp, error) {
+	cs, err := s.loadChirps(userID)
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	id, err := strconv.Atoi(chirpID)
+	if err != nil {
+		return ResChirp{}, ErrChirpNotFound
+	}
+	c, ok := cs.byID[id]
+	if !ok || !cs.visible(c) {
+		return ResChirp{}, ErrChirpNotFound
+	}
+	if c.AuthorID != userID {
+		return ResChirp{}, ErrNotAuthor
+	}
+	if c.Kind == db.KindRechirp {
+		return ResChirp{}, ErrNotEditable
+	}
+	if time.Since(c.CreatedAt) > s.editWindow || c.Held {
+		return ResChirp{}, ErrEditWindowClosed
+	}
+
+	before := c
+	c.Body = body
+	c, checks, err := s.checkChirp(c)
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	c, err = s.dbConn.EditChirp(c)
+	if err != nil {
+		return ResChirp{}, err
+	}
+	cs.byID[c.ID] = c
+
+	err = s.flagChirp(c, checks.flagged)
+	if err != nil {
+		return ResChirp{}, err
+	}
+	err = s.recordSpamVerdict(c, checks.spam)
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	s.trends.Remove(c.ID)
+	if c.Held {
+		// Whoever could see the chirp before the edit has to drop it
+		s.index.RemoveChirp(c.ID)
+		s.publishChirpEvent(stream.EventDelete, before, deletedEvent(c))
+		return cs.render(c), nil
+	}
+	s.trackHashtags(c, cs.shadowBanned[c.AuthorID] || cs.protected[c.AuthorID])
+	s.indexChirp(c)
+	for _, m := range c.Mentions {
+		if !slices.ContainsFunc(before.Mentions, func(p db.Mention) bool { return p.UserID == m.UserID }) {
+			s.notify(m.UserID, c.AuthorID, db.NotifyMention, c.ID)
+		}
+	}
+	s.publishChirpEvent(stream.EventEdit, c, func(cs chirpSet) any { return cs.render(c) })
+	return cs.render(c), nil
+}
+
+// GetChirpHistory returns every version of a chirp's body, oldest first, with
+// the current version last
+func (s *Service) GetChirpHistory(viewerID int, chirpID string) ([]ResChirpVersion, error) {
+	chirp, ok := s.GetChirp(viewerID, chirpID)
+	if !ok {
+		return nil, ErrChirpNotFound
+	}
+
+	versions, err := s.dbConn.GetChirpVersions(chirp.ID)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(versions, func(a, b db.ChirpVersion) int { return a.Version - b.Version })
+
+	history := []ResChirpVersion{}
+	for _, v := range versions {
+		history = append(history, ResChirpVersion{
+			Version:   v.Version,
+			Body:      v.Body,
+			Mentions:  v.Mentions,
+			Hashtags:  v.Hashtags,
+			CreatedAt: v.CreatedAt,
+		})
+	}
+
+	current := ResChirpVersion{
+		Version:   len(versions) + 1,
+		Body:      chirp.Body,
+		Mentions:  chirp.Mentions,
+		Hashtags:  chirp.Hashtags,
+		CreatedAt: chirp.CreatedAt,
+		Current:   true,
+	}
+	if chirp.EditedAt != nil {
+		current.CreatedAt = *chirp.EditedAt
+	}
+	return append(history, current), nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/graphemes"
+)
+
+// isWordRune reports whether r can be part of a mention or hashtag. It is also
+// used to make sure a "@" or "#" starts a new word (so that "a@b" isn't a
+// mention).
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// isMentionRune reports whether r can be part of a mention. Mentions may be
+// emails, so dots, dashes, pluses and a second "@" are allowed.
+func isMentionRune(r rune) bool {
+	return isWordRune(r) || strings.ContainsRune(".-+@", r)
+}
+
+// parseEntities extracts mentions and hashtags from a chirp body. Mentions are
+// resolved against the given users by handle or email (case-insensitive);
+// mentions that don't match any user are left as plain text. Offsets are in
+// grapheme clusters, the same unit chirp length is counted in.
+func parseEntities(body string, users []db.User) ([]db.Mention, []db.Hashtag) {
+	mentions := []db.Mention{}
+	hashtags := []db.Hashtag{}
+	runes := []rune(body)
+
+	// cluster maps every rune to the grapheme cluster it is part of
+	cluster := make([]int, 0, len(runes))
+	for i, c := range graphemes.Split(body) {
+		for range []rune(c) {
+			cluster = append(cluster, i)
+		}
+	}
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' && runes[i] != '#' {
+			continue
+		}
+		if i > 0 && isWordRune(runes[i-1]) {
+			continue
+		}
+
+		isValid := isWordRune
+		if runes[i] == '@' {
+			isValid = isMentionRune
+		}
+
+		end := i + 1
+		for end < len(runes) && isValid(runes[end]) {
+			end++
+		}
+		// Trailing punctuation usually ends the sentence, e.g. "hi @a@b.com."
+		for end > i+1 && strings.ContainsRune(".-+@", runes[end-1]) {
+			end--
+		}
+		if end == i+1 {
+			continue
+		}
+
+		text := string(runes[i+1 : end])
+		if runes[i] == '#' {
+			if strings.IndexFunc(text, unicode.IsLetter) == -1 {
+				continue
+			}
+			hashtags = append(hashtags, db.Hashtag{
+				Start: cluster[i],
+				End:   cluster[end-1] + 1,
+				Tag:   strings.ToLower(text),
+			})
+		} else if userID, ok := resolveMention(text, users); ok {
+			mentions = append(mentions, db.Mention{
+				Start:  cluster[i],
+				End:    cluster[end-1] + 1,
+				Text:   text,
+				UserID: userID,
+			})
+		}
+		i = end - 1
+	}
+
+	return mentions, hashtags
+}
+
+// resolveMention returns the ID of the user referred to by a mention
+func resolveMention(text string, users []db.User) (int, bool) {
+	for _, u := range users {
+		if strings.EqualFold(u.Handle, text) || strings.EqualFold(u.Email, text) {
+			return u.ID, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"os"
+	"slices"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/filter"
+)
+
+// InitFilter creates the content filter from the rules saved in the database.
+// If there are none yet, the database is seeded from the JSON config file at
+// FILTER_CONFIG, or from the default rules if that isn't set.
+func (s *Service) InitFilter() {
+	saved, err := s.dbConn.GetFilterRules()
+	if err != nil {
+		panic(err)
+	}
+
+	rules := []filter.Rule{}
+	for _, r := range saved {
+		rules = append(rules, filter.Rule{Word: r.Word, Action: r.Action})
+	}
+	seed := len(rules) == 0
+	if seed {
+		rules = filter.DefaultRules
+		if path := os.Getenv("FILTER_CONFIG"); path != "" {
+			rules, err = filter.LoadConfig(path)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	s.filter, err = filter.New(rules)
+	if err != nil {
+		panic(err)
+	}
+	if !seed {
+		return
+	}
+	for _, r := range s.filter.Rules() {
+		err = s.dbConn.SetFilterRule(db.FilterRule{Word: r.Word, Action: r.Action})
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// filterBody runs a chirp body through the content filter, returning the
+// body with masked words replaced and the words it should be flagged for
+func (s *Service) filterBody(body string) (string, []string, error) {
+	res := s.filter.Check(body)
+	if res.Rejected {
+		return "", nil, ErrRejectedContent
+	}
+	return res.Body, res.Flagged, nil
+}
+
+// flagChirp records a new chirp for review if it contains flagged words
+func (s *Service) flagChirp(c db.Chirp, words []string) error {
+	if len(words) == 0 {
+		return nil
+	}
+	_, err := s.dbConn.CreateContentFlag(c.ID, c.AuthorID, words)
+	return err
+}
+
+// GetFilterRules returns the content filter rules sorted by word to an admin
+func (s *Service) GetFilterRules(adminID int) ([]filter.Rule, error) {
+	err := s.RequireRole(adminID, db.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return s.filter.Rules(), nil
+}
+
+// SetFilterRule adds a banned word to the content filter or changes the
+// action taken for it on behalf of an admin
+func (s *Service) SetFilterRule(adminID int, word string, action string, ip string) (filter.Rule, error) {
+	err := s.RequireRole(adminID, db.RoleAdmin)
+	if err != nil {
+		return filter.Rule{}, err
+	}
+
+	rule, err := s.filter.Set(filter.Rule{Word: word, Action: action})
+	if err != nil {
+		return rule, ErrInvalidFilterRule
+	}
+	err = s.dbConn.SetFilterRule(db.FilterRule{Word: rule.Word, Action: rule.Action})
+	if err != nil {
+		return rule, err
+	}
+	s.record(AuditFilterRuleSet, adminID, ip, "", map[string]string{
+		"word":   rule.Word,
+		"action": rule.Action,
+	})
+	return rule, nil
+}
+
+// DeleteFilterRule removes a banned word from the content filter on behalf of
+// an admin
+func (s *Service) DeleteFilterRule(adminID int, word string, ip string) error {
+	err := s.RequireRole(adminID, db.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	if !s.filter.Remove(word) {
+		return ErrFilterRuleNotFound
+	}
+	err = s.dbConn.DeleteFilterRule(filter.Normalize(word))
+	if err != nil {
+		return err
+	}
+	s.record(AuditFilterRuleDeleted, adminID, ip, "", map[string]string{"word": filter.Normalize(word)})
+	return nil
+}
+
+// GetContentFlags returns the chirps flagged for review, newest first, to a
+// moderator
+func (s *Service) GetContentFlags(moderatorID int) ([]db.ContentFlag, error) {
+	err := s.RequireRole(moderatorID, db.RoleModerator)
+	if err != nil {
+		return nil, err
+	}
+
+	flags, err := s.dbConn.GetContentFlags()
+	if err != nil {
+		return flags, err
+	}
+	slices.SortFunc(flags, func(a, b db.ContentFlag) int { return b.ID - a.ID })
+	return flags, nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/graphemes"
+)
+
+// Default chirp length limits for regular and Chirpy Red users
+const (
+	defaultMaxChirpLen    = 140
+	defaultMaxChirpLenRed = 280
+)
+
+// urlWeight is how many characters a URL counts as, however long it is
+const urlWeight = 23
+
+var urlRe = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// InitLimits reads the chirp length limits from the CHIRP_MAX_LENGTH and
+// CHIRP_MAX_LENGTH_RED environment variables, defaulting to 140 and 280
+// characters respectively, and the edit window from CHIRP_EDIT_WINDOW (e.g.
+// "5m"), defaulting to 15 minutes.
+func (s *Service) InitLimits() {
+	s.maxChirpLen = envInt("CHIRP_MAX_LENGTH", defaultMaxChirpLen)
+	s.maxChirpLenRed = envInt("CHIRP_MAX_LENGTH_RED", defaultMaxChirpLenRed)
+
+	s.editWindow = defaultEditWindow
+	if env := os.Getenv("CHIRP_EDIT_WINDOW"); env != "" {
+		d, err := time.ParseDuration(env)
+		if err != nil {
+			panic(err)
+		}
+		s.editWindow = d
+	}
+}
+
+// envInt reads a positive integer from the environment variable of the given
+// name, returning def if it isn't set
+func envInt(name string, def int) int {
+	env := os.Getenv(name)
+	if env == "" {
+		return def
+	}
+	n, err := strconv.Atoi(env)
+	if err != nil || n <= 0 {
+		panic(name + " must be a positive integer")
+	}
+	return n
+}
+
+// chirpLength returns the length of a chirp body in user-perceived
+// characters, with every URL counted as urlWeight characters
+func chirpLength(body string) int {
+	urls := urlRe.FindAllStringIndex(body, -1)
+	length := len(urls) * urlWeight
+	prev := 0
+	for _, u := range urls {
+		length += graphemes.Count(body[prev:u[0]])
+		prev = u[1]
+	}
+	return length + graphemes.Count(body[prev:])
+}
+
+// maxChirpLength returns how long the chirps of regular or Chirpy Red users
+// may be
+func (s *Service) maxChirpLength(isChirpyRed bool) int {
+	if isChirpyRed {
+		return s.maxChirpLenRed
+	}
+	return s.maxChirpLen
+}
+
+// checkLength returns ErrChirpTooLong if the body is over the length limit of
+// the given author
+func (s *Service) checkLength(authorID int, body string) error {
+	u, err := s.dbConn.GetUser(authorID)
+	if err != nil {
+		return err
+	}
+	if chirpLength(body) > s.maxChirpLength(u.IsChirpyRed) {
+		return ErrChirpTooLong
+	}
+	return nil
+}
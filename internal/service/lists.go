@@ -0,0 +1,303 @@
+package service
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/graphemes"
+)
+
+// List limits
+const (
+	maxListNameLen        = 25
+	maxListDescriptionLen = 100
+)
+
+// ResList holds a list's details to be used by handlers in HTTP responses
+type ResList struct {
+	ID          int        `json:"id"`
+	Owner       *ResAuthor `json:"owner"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Private     bool       `json:"private"`
+	MemberCount int        `json:"member_count"`
+}
+
+// Bookmark privately saves the chirp of the given ID for the user
+func (s *Service) Bookmark(userID int, chirpID string) error {
+	cs, err := s.loadChirps(userID)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(chirpID)
+	if err != nil {
+		return ErrChirpNotFound
+	}
+	if c, ok := cs.byID[id]; !ok || !cs.visible(c) {
+		return ErrChirpNotFound
+	}
+
+	return s.dbConn.CreateBookmark(userID, id)
+}
+
+// Unbookmark removes the user's bookmark of the chirp of the given ID
+func (s *Service) Unbookmark(userID int, chirpID string) error {
+	id, err := strconv.Atoi(chirpID)
+	if err != nil {
+		return ErrChirpNotFound
+	}
+	return s.dbConn.DeleteBookmark(userID, id)
+}
+
+// GetBookmarks returns the chirps the user bookmarked, most recently
+// bookmarked first. Chirps the user can no longer see are left out.
+func (s *Service) GetBookmarks(userID int) ([]ResChirp, error) {
+	chirps := []ResChirp{}
+	cs, err := s.loadChirps(userID)
+	if err != nil {
+		return chirps, err
+	}
+
+	bookmarks, err := s.dbConn.GetBookmarks()
+	if err != nil {
+		return chirps, err
+	}
+	slices.SortFunc(bookmarks, func(a, b db.Bookmark) int { return b.ID - a.ID })
+
+	for _, b := range bookmarks {
+		if b.UserID != userID {
+			continue
+		}
+		if c, ok := cs.byID[b.ChirpID]; ok && cs.visible(c) {
+			chirps = append(chirps, cs.render(c))
+		}
+	}
+	return chirps, nil
+}
+
+// validateList trims a list's name and description and checks their length
+func validateList(name *string, description *string) error {
+	*name = strings.TrimSpace(*name)
+	*description = strings.TrimSpace(*description)
+	nameLen := graphemes.Count(*name)
+	if nameLen == 0 || nameLen > maxListNameLen || graphemes.Count(*description) > maxListDescriptionLen {
+		return ErrInvalidList
+	}
+	return nil
+}
+
+// toResList renders a list along with its owner
+func (s *Service) toResList(l db.List) (ResList, error) {
+	owner, err := s.dbConn.GetUser(l.OwnerID)
+	if err != nil {
+		return ResList{}, err
+	}
+	resOwner := toResAuthor(owner)
+	return ResList{
+		ID:          l.ID,
+		Owner:       &resOwner,
+		Name:        l.Name,
+		Description: l.Description,
+		Private:     l.Private,
+		MemberCount: len(l.MemberIDs),
+	}, nil
+}
+
+// listOf returns the list of the given ID if the viewer can see it, which is
+// the case for public lists and the viewer's own lists
+func (s *Service) listOf(viewerID int, listID string) (db.List, error) {
+	id, err := strconv.Atoi(listID)
+	if err != nil {
+		return db.List{}, ErrListNotFound
+	}
+
+	lists, err := s.dbConn.GetLists()
+	if err != nil {
+		return db.List{}, err
+	}
+	i := slices.IndexFunc(lists, func(l db.List) bool { return l.ID == id })
+	if i == -1 || (lists[i].Private && lists[i].OwnerID != viewerID) {
+		return db.List{}, ErrListNotFound
+	}
+
+	l := lists[i]
+	blocked, err := s.isBlocked(viewerID, l.OwnerID)
+	if err != nil {
+		return l, err
+	}
+	if blocked {
+		return l, ErrListNotFound
+	}
+	return l, nil
+}
+
+// ownListOf returns the list of the given ID if it belongs to the user
+func (s *Service) ownListOf(userID int, listID string) (db.List, error) {
+	l, err := s.listOf(userID, listID)
+	if err != nil {
+		return l, err
+	}
+	if l.OwnerID != userID {
+		return l, ErrNotListOwner
+	}
+	return l, nil
+}
+
+// CreateList creates a new, empty list for the user
+func (s *Service) CreateList(ownerID int, name string, description string, private bool) (ResList, error) {
+	err := validateList(&name, &description)
+	if err != nil {
+		return ResList{}, err
+	}
+
+	l, err := s.dbConn.SaveList(db.List{
+		OwnerID:     ownerID,
+		Name:        name,
+		Description: description,
+		Private:     private,
+	})
+	if err != nil {
+		return ResList{}, err
+	}
+	return s.toResList(l)
+}
+
+// UpdateList changes the name, description and privacy of one of the user's
+// lists
+func (s *Service) UpdateList(userID int, listID string, name string, description string, private bool) (ResList, error) {
+	l, err := s.ownListOf(userID, listID)
+	if err != nil {
+		return ResList{}, err
+	}
+
+	err = validateList(&name, &description)
+	if err != nil {
+		return ResList{}, err
+	}
+
+	l.Name = name
+	l.Description = description
+	l.Private = private
+	l, err = s.dbConn.SaveList(l)
+	if err != nil {
+		return ResList{}, err
+	}
+	return s.toResList(l)
+}
+
+// DeleteList deletes one of the user's lists
+func (s *Service) DeleteList(userID int, listID string) error {
+	l, err := s.ownListOf(userID, listID)
+	if err != nil {
+		return err
+	}
+	return s.dbConn.DeleteList(l.ID)
+}
+
+// GetLists returns the lists owned by the given user that the viewer can see,
+// oldest first
+func (s *Service) GetLists(viewerID int, ownerID int) ([]ResList, error) {
+	out := []ResList{}
+	lists, err := s.dbConn.GetLists()
+	if err != nil {
+		return out, err
+	}
+	slices.SortFunc(lists, func(a, b db.List) int { return a.ID - b.ID })
+
+	for _, l := range lists {
+		if l.OwnerID != ownerID || (l.Private && l.OwnerID != viewerID) {
+			continue
+		}
+		resList, err := s.toResList(l)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, resList)
+	}
+	return out, nil
+}
+
+// GetList returns the details of a list the viewer can see
+func (s *Service) GetList(viewerID int, listID string) (ResList, error) {
+	l, err := s.listOf(viewerID, listID)
+	if err != nil {
+		return ResList{}, err
+	}
+	return s.toResList(l)
+}
+
+// AddListMember adds a user to one of the user's lists. Users blocked either
+// way can't be added.
+func (s *Service) AddListMember(userID int, listID string, memberID int) (ResList, error) {
+	l, err := s.ownListOf(userID, listID)
+	if err != nil {
+		return ResList{}, err
+	}
+
+	_, err = s.dbConn.GetUser(memberID)
+	if err != nil {
+		return ResList{}, ErrUserNotFound
+	}
+	blocked, err := s.isBlocked(userID, memberID)
+	if err != nil {
+		return ResList{}, err
+	}
+	if blocked {
+		return ResList{}, ErrBlocked
+	}
+
+	l, err = s.dbConn.AddListMember(l.ID, memberID)
+	if err != nil {
+		return ResList{}, err
+	}
+	return s.toResList(l)
+}
+
+// RemoveListMember removes a user from one of the user's lists
+func (s *Service) RemoveListMember(userID int, listID string, memberID int) (ResList, error) {
+	l, err := s.ownListOf(userID, listID)
+	if err != nil {
+		return ResList{}, err
+	}
+
+	l, err = s.dbConn.RemoveListMember(l.ID, memberID)
+	if err != nil {
+		return ResList{}, ErrUserNotFound
+	}
+	return s.toResList(l)
+}
+
+// GetListMembers returns the members of a list the viewer can see
+func (s *Service) GetListMembers(viewerID int, listID string) ([]ResAuthor, error) {
+	l, err := s.listOf(viewerID, listID)
+	if err != nil {
+		return nil, err
+	}
+	return s.authorsOf(l.MemberIDs)
+}
+
+// GetListTimeline returns the chirps by a list's members listed for the
+// viewer, newest first
+func (s *Service) GetListTimeline(viewerID int, listID string) ([]ResChirp, error) {
+	chirps := []ResChirp{}
+	l, err := s.listOf(viewerID, listID)
+	if err != nil {
+		return chirps, err
+	}
+
+	cs, err := s.loadChirps(viewerID)
+	if err != nil {
+		return chirps, err
+	}
+	for _, c := range cs.byID {
+		if slices.Contains(l.MemberIDs, c.AuthorID) && cs.inTimeline(c) {
+			chirps = append(chirps, cs.render(c))
+		}
+	}
+
+	slices.SortFunc(chirps, sortChirpsDesc)
+	return chirps, nil
+}
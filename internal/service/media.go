@@ -0,0 +1,163 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/media"
+)
+
+// MediaDir is the directory the local blob store keeps uploads in. It is
+// served under /media/.
+const MediaDir = "media"
+
+// maxMediaPerChirp is the largest number of images a chirp can carry
+const maxMediaPerChirp = 4
+
+// ResMedia holds an uploaded image to be used by handlers in HTTP responses
+type ResMedia struct {
+	ID           int    `json:"id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ContentType  string `json:"content_type"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+// InitMedia sets up the local blob store used for uploads
+func (s *Service) InitMedia() {
+	blobs, err := media.NewLocalStore(MediaDir, "/media/")
+	if err != nil {
+		panic(err)
+	}
+	s.blobs = blobs
+}
+
+func (s *Service) toResMedia(m db.Media) ResMedia {
+	return ResMedia{
+		ID:           m.ID,
+		URL:          s.blobs.URL(m.Key),
+		ThumbnailURL: s.blobs.URL(m.ThumbnailKey),
+		ContentType:  m.ContentType,
+		Width:        m.Width,
+		Height:       m.Height,
+	}
+}
+
+// UploadMedia processes and stores an uploaded image for the given user. The
+// returned media ID can then be attached to one of the user's chirps.
+func (s *Service) UploadMedia(ownerID int, image []byte) (ResMedia, error) {
+	processed, err := media.Process(image)
+	if errors.Is(err, media.ErrUnsupportedImage) {
+		return ResMedia{}, ErrInvalidImage
+	}
+	if err != nil {
+		return ResMedia{}, err
+	}
+
+	nameBytes := make([]byte, 16)
+	_, err = rand.Read(nameBytes)
+	if err != nil {
+		return ResMedia{}, err
+	}
+	name := fmt.Sprintf("chirps/%d/%s", ownerID, hex.EncodeToString(nameBytes))
+
+	thumbExt := ".png"
+	if processed.Ext == ".jpg" {
+		thumbExt = ".jpg"
+	}
+	m := db.Media{
+		OwnerID:      ownerID,
+		Key:          name + processed.Ext,
+		ThumbnailKey: name + "_thumb" + thumbExt,
+		ContentType:  processed.ContentType,
+		Width:        processed.Width,
+		Height:       processed.Height,
+	}
+
+	err = s.blobs.Put(m.Key, processed.Data)
+	if err != nil {
+		return ResMedia{}, err
+	}
+	err = s.blobs.Put(m.ThumbnailKey, processed.Thumbnail)
+	if err != nil {
+		return ResMedia{}, err
+	}
+
+	m, err = s.dbConn.CreateMedia(m)
+	if err != nil {
+		return ResMedia{}, err
+	}
+	return s.toResMedia(m), nil
+}
+
+// GetMediaBlob returns the stored file under the given key if the viewer (0
+// for anonymous viewers) can see it. Avatars are public, chirp media is only
+// visible to those who can see the chirp and media not attached to a chirp yet
+// only to its owner. Anything else is reported as not found.
+func (s *Service) GetMediaBlob(viewerID int, key string) ([]byte, error) {
+	if name, ok := strings.CutPrefix(key, "avatars/"); ok {
+		idStr, _, _ := strings.Cut(name, ".")
+		userID, err := strconv.Atoi(idStr)
+		if err != nil || !slices.Contains(avatarKeys(userID), key) {
+			return nil, ErrMediaNotFound
+		}
+		return s.getBlob(key)
+	}
+
+	mediaList, err := s.dbConn.GetMedia()
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(mediaList, func(m db.Media) bool { return m.Key == key || m.ThumbnailKey == key })
+	if i == -1 {
+		return nil, ErrMediaNotFound
+	}
+	m := mediaList[i]
+
+	if m.ChirpID == 0 {
+		if m.OwnerID != viewerID {
+			return nil, ErrMediaNotFound
+		}
+		return s.getBlob(key)
+	}
+
+	cs, err := s.loadChirps(viewerID)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := cs.byID[m.ChirpID]
+	if !ok || !cs.canSee(c) {
+		return nil, ErrMediaNotFound
+	}
+	return s.getBlob(key)
+}
+
+// getBlob reads a blob, reporting missing blobs as not found
+func (s *Service) getBlob(key string) ([]byte, error) {
+	data, err := s.blobs.Get(key)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrMediaNotFound
+	}
+	return data, err
+}
+
+// deleteChirpBlobs removes the blobs of the given media records. Failing to
+// remove a blob only leaves an orphan file behind, so errors are only logged.
+func (s *Service) deleteChirpBlobs(mediaList []db.Media) {
+	for _, m := range mediaList {
+		for _, key := range []string{m.Key, m.ThumbnailKey} {
+			err := s.blobs.Delete(key)
+			if err != nil {
+				fmt.Println("Error deleting blob:", err)
+			}
+		}
+	}
+}
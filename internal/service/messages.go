@@ -0,0 +1,272 @@
+package service
+
+import (
+	"slices"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/stream"
+)
+
+// maxParticipants is the largest number of users (including the creator) a
+// conversation can have
+const maxParticipants = 8
+
+// ResConversation holds a conversation to be used by handlers in HTTP
+// responses, along with its latest message and the number of messages the
+// requesting user hasn't read
+type ResConversation struct {
+	db.Conversation
+	LastMessage *db.Message `json:"last_message"`
+	UnreadCount int         `json:"unread_count"`
+}
+
+// ResMessages holds a page of messages, newest first
+type ResMessages struct {
+	Messages []db.Message `json:"messages"`
+	HasMore  bool         `json:"has_more"`
+}
+
+// StartConversation creates a conversation between the creator and the given
+// users. Every other participant must either follow the creator or accept
+// messages from anyone. Starting a one-to-one conversation that already
+// exists returns the existing one.
+func (s *Service) StartConversation(creatorID int, userIDs []int) (db.Conversation, error) {
+	participantIDs := []int{creatorID}
+	for _, id := range userIDs {
+		if !slices.Contains(participantIDs, id) {
+			participantIDs = append(participantIDs, id)
+		}
+	}
+	if len(participantIDs) < 2 || len(participantIDs) > maxParticipants {
+		return db.Conversation{}, ErrInvalidParticipants
+	}
+
+	users, err := s.dbConn.GetUsers()
+	if err != nil {
+		return db.Conversation{}, err
+	}
+	follows, err := s.dbConn.GetFollows()
+	if err != nil {
+		return db.Conversation{}, err
+	}
+
+	blocked, _, err := s.relationsOf(creatorID)
+	if err != nil {
+		return db.Conversation{}, err
+	}
+
+	for _, id := range participantIDs[1:] {
+		i := slices.IndexFunc(users, func(u db.User) bool { return u.ID == id })
+		if i == -1 {
+			return db.Conversation{}, ErrUserNotFound
+		}
+		if blocked[id] {
+			return db.Conversation{}, ErrBlocked
+		}
+
+		if !acceptsMessages(users[i], creatorID, follows) {
+			return db.Conversation{}, ErrDMNotAllowed
+		}
+	}
+
+	if len(participantIDs) == 2 {
+		convs, err := s.dbConn.GetConversations()
+		if err != nil {
+			return db.Conversation{}, err
+		}
+		for _, c := range convs {
+			if len(c.ParticipantIDs) == 2 &&
+				slices.Contains(c.ParticipantIDs, participantIDs[0]) &&
+				slices.Contains(c.ParticipantIDs, participantIDs[1]) {
+				return c, nil
+			}
+		}
+	}
+
+	return s.dbConn.CreateConversation(participantIDs)
+}
+
+// acceptsMessages reports whether the user accepts messages from the sender,
+// either because they follow the sender or because they accept messages from
+// anyone
+func acceptsMessages(u db.User, senderID int, follows []db.Follow) bool {
+	return u.OpenDMs || slices.ContainsFunc(follows, func(f db.Follow) bool {
+		return f.FollowerID == u.ID && f.FolloweeID == senderID
+	})
+}
+
+// conversationOf returns the conversation of the given ID if the user takes
+// part in it. Conversations the user isn't part of are reported as not found
+// so their existence isn't leaked.
+func (s *Service) conversationOf(userID int, conversationID int) (db.Conversation, error) {
+	convs, err := s.dbConn.GetConversations()
+	if err != nil {
+		return db.Conversation{}, err
+	}
+
+	for _, c := range convs {
+		if c.ID == conversationID && slices.Contains(c.ParticipantIDs, userID) {
+			return c, nil
+		}
+	}
+	return db.Conversation{}, ErrConversationNotFound
+}
+
+// GetConversations returns the conversations the user takes part in, most
+// recently active first
+func (s *Service) GetConversations(userID int) ([]ResConversation, error) {
+	out := []ResConversation{}
+
+	convs, err := s.dbConn.GetConversations()
+	if err != nil {
+		return out, err
+	}
+
+	for _, c := range convs {
+		if !slices.Contains(c.ParticipantIDs, userID) {
+			continue
+		}
+
+		msgs, err := s.dbConn.GetMessages(c.ID)
+		if err != nil {
+			return out, err
+		}
+
+		resConv := ResConversation{Conversation: c}
+		for _, m := range msgs {
+			if m.ID > c.LastRead[userID] {
+				resConv.UnreadCount++
+			}
+			if resConv.LastMessage == nil || m.ID > resConv.LastMessage.ID {
+				lastMessage := m
+				resConv.LastMessage = &lastMessage
+			}
+		}
+		out = append(out, resConv)
+	}
+
+	slices.SortFunc(out, func(a, b ResConversation) int {
+		aLatest, bLatest := a.CreatedAt, b.CreatedAt
+		if a.LastMessage != nil {
+			aLatest = a.LastMessage.CreatedAt
+		}
+		if b.LastMessage != nil {
+			bLatest = b.LastMessage.CreatedAt
+		}
+		return bLatest.Compare(aLatest)
+	})
+	return out, nil
+}
+
+// SendMessage adds a message from the user to the conversation and pushes it
+// to the participants' streams. Users can't message conversations with users
+// they blocked or were blocked by. Like when starting a conversation, every
+// other participant must still accept messages from the user, unless they
+// started the conversation or have written in it themselves.
+func (s *Service) SendMessage(userID int, conversationID int, body string) (db.Message, error) {
+	conv, err := s.conversationOf(userID, conversationID)
+	if err != nil {
+		return db.Message{}, err
+	}
+
+	blocked, _, err := s.relationsOf(userID)
+	if err != nil {
+		return db.Message{}, err
+	}
+	if slices.ContainsFunc(conv.ParticipantIDs, func(id int) bool { return blocked[id] }) {
+		return db.Message{}, ErrBlocked
+	}
+
+	users, err := s.dbConn.GetUsers()
+	if err != nil {
+		return db.Message{}, err
+	}
+	follows, err := s.dbConn.GetFollows()
+	if err != nil {
+		return db.Message{}, err
+	}
+	msgs, err := s.dbConn.GetMessages(conv.ID)
+	if err != nil {
+		return db.Message{}, err
+	}
+	for _, u := range users {
+		if u.ID == userID || u.ID == conv.ParticipantIDs[0] || !slices.Contains(conv.ParticipantIDs, u.ID) {
+			continue
+		}
+		wrote := slices.ContainsFunc(msgs, func(m db.Message) bool { return m.SenderID == u.ID })
+		if !wrote && !acceptsMessages(u, userID, follows) {
+			return db.Message{}, ErrDMNotAllowed
+		}
+	}
+
+	msg, err := s.dbConn.CreateMessage(conv.ID, userID, body)
+	if err != nil {
+		return db.Message{}, err
+	}
+
+	s.hub.Publish(stream.EventMessage, msg, conv.ParticipantIDs)
+	return msg, nil
+}
+
+// GetMessages returns a page of up to limit messages in the conversation, newest
+// first. If beforeID is not zero, only messages older than it are returned.
+func (s *Service) GetMessages(userID int, conversationID int, beforeID int, limit int) (ResMessages, error) {
+	out := ResMessages{Messages: []db.Message{}}
+
+	conv, err := s.conversationOf(userID, conversationID)
+	if err != nil {
+		return out, err
+	}
+
+	msgs, err := s.dbConn.GetMessages(conv.ID)
+	if err != nil {
+		return out, err
+	}
+
+	slices.SortFunc(msgs, func(a, b db.Message) int { return b.ID - a.ID })
+	for _, m := range msgs {
+		if beforeID != 0 && m.ID >= beforeID {
+			continue
+		}
+		if len(out.Messages) == limit {
+			out.HasMore = true
+			break
+		}
+		out.Messages = append(out.Messages, m)
+	}
+	return out, nil
+}
+
+// MarkConversationRead records that the user has read every message in the
+// conversation so far, and lets the other participants know
+func (s *Service) MarkConversationRead(userID int, conversationID int) (db.Conversation, error) {
+	conv, err := s.conversationOf(userID, conversationID)
+	if err != nil {
+		return db.Conversation{}, err
+	}
+
+	msgs, err := s.dbConn.GetMessages(conv.ID)
+	if err != nil {
+		return db.Conversation{}, err
+	}
+
+	latestID := 0
+	for _, m := range msgs {
+		latestID = max(latestID, m.ID)
+	}
+
+	conv, err = s.dbConn.MarkConversationRead(conv.ID, userID, latestID)
+	if err != nil {
+		return db.Conversation{}, err
+	}
+
+	s.hub.Publish(stream.EventRead, conv, conv.ParticipantIDs)
+	return conv, nil
+}
+
+// SetOpenDMs sets whether users the given user doesn't follow can message
+// them
+func (s *Service) SetOpenDMs(userID int, open bool) error {
+	_, err := s.dbConn.SetOpenDMs(userID, open)
+	return err
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/graphemes"
+)
+
+// SuspensionError is returned for suspended users, with the reason they were
+// given and, for temporary suspensions, when the suspension ends. It matches
+// ErrSuspended with errors.Is.
+type SuspensionError struct {
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"suspended_until,omitempty"`
+}
+
+func (e *SuspensionError) Error() string {
+	return ErrSuspended.Error()
+}
+
+func (e *SuspensionError) Unwrap() error {
+	return ErrSuspended
+}
+
+// isSuspended reports whether the user's suspension, if any, is still in
+// effect
+func isSuspended(u db.User) bool {
+	return u.Suspended && (u.SuspendedUntil == nil || time.Now().Before(*u.SuspendedUntil))
+}
+
+// checkStatus returns an error if the user can't use their account with a
+// token issued at the given time, either because the account no longer
+// exists, because it's suspended or because its sessions were revoked after
+// the token was issued
+func (s *Service) checkStatus(userID int, issuedAt time.Time) error {
+	u, err := s.dbConn.GetUser(userID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if u.SessionsRevokedAt != nil && issuedAt.Before(*u.SessionsRevokedAt) {
+		return ErrSessionRevoked
+	}
+	if isSuspended(u) {
+		return &SuspensionError{Reason: u.SuspensionReason, Until: u.SuspendedUntil}
+	}
+	return nil
+}
+
+// moderatable returns the user of the given ID if moderators can act on
+// them. Moderators and admins can't be suspended or shadow-banned.
+func (s *Service) moderatable(userID int) (db.User, error) {
+	u, err := s.dbConn.GetUser(userID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return u, ErrUserNotFound
+	}
+	if err != nil {
+		return u, err
+	}
+	if hasRole(u, db.RoleModerator) {
+		return u, ErrForbidden
+	}
+	return u, nil
+}
+
+// SuspendUser suspends a user outside of a report, for the given duration or
+// indefinitely if it's 0, with the reason shown to them when they try to sign
+// in. Their tokens stop working and their streams are closed right away. Like
+// every moderation action, suspensions are audited.
+func (s *Service) SuspendUser(moderatorID int, userID int, reason string, duration time.Duration, ip string) (db.ModAction, error) {
+	err := s.RequireRole(moderatorID, db.RoleModerator)
+	if err != nil {
+		return db.ModAction{}, err
+	}
+
+	reason = strings.TrimSpace(reason)
+	if graphemes.Count(reason) > maxReportReasonLen || duration < 0 {
+		return db.ModAction{}, ErrInvalidModAction
+	}
+	_, err = s.moderatable(userID)
+	if err != nil {
+		return db.ModAction{}, err
+	}
+
+	a := db.ModAction{ModeratorID: moderatorID, Action: db.ModSuspend, UserID: userID, Note: reason}
+	if duration > 0 {
+		until := time.Now().Add(duration)
+		a.Until = &until
+	}
+	a, err = s.dbConn.CreateModAction(a, "", nil)
+	if err != nil {
+		return a, err
+	}
+	s.recordModAction(a, ip)
+	return a, s.applyModAction(a)
+}
+
+// UnsuspendUser lifts a user's suspension
+func (s *Service) UnsuspendUser(moderatorID int, userID int, ip string) (db.ModAction, error) {
+	err := s.RequireRole(moderatorID, db.RoleModerator)
+	if err != nil {
+		return db.ModAction{}, err
+	}
+
+	u, err := s.moderatable(userID)
+	if err != nil {
+		return db.ModAction{}, err
+	}
+	if !isSuspended(u) {
+		return db.ModAction{}, ErrNotSuspended
+	}
+
+	a, err := s.dbConn.CreateModAction(db.ModAction{
+		ModeratorID: moderatorID,
+		Action:      db.ModUnsuspend,
+		UserID:      userID,
+	}, "", nil)
+	if err != nil {
+		return a, err
+	}
+	s.recordModAction(a, ip)
+	return a, s.applyModAction(a)
+}
+
+// SetShadowBanned shadow-bans a user, hiding their chirps from everyone but
+// themselves, or lifts their shadow ban
+func (s *Service) SetShadowBanned(moderatorID int, userID int, banned bool, ip string) (db.ModAction, error) {
+	err := s.RequireRole(moderatorID, db.RoleModerator)
+	if err != nil {
+		return db.ModAction{}, err
+	}
+
+	_, err = s.moderatable(userID)
+	if err != nil {
+		return db.ModAction{}, err
+	}
+
+	action := db.ModShadowBan
+	if !banned {
+		action = db.ModUnshadowBan
+	}
+	a, err := s.dbConn.CreateModAction(db.ModAction{
+		ModeratorID: moderatorID,
+		Action:      action,
+		UserID:      userID,
+	}, "", nil)
+	if err != nil {
+		return a, err
+	}
+	s.recordModAction(a, ip)
+	return a, s.applyModAction(a)
+}
+
+// retrackChirps adds or removes the user's chirps from the trends after
+// their shadow ban or protected status changed
+func (s *Service) retrackChirps(u db.User) error {
+	chirps, err := s.dbConn.GetChirps()
+	if err != nil {
+		return err
+	}
+	for _, c := range chirps {
+		if c.AuthorID != u.ID {
+			continue
+		}
+		s.trends.Remove(c.ID)
+		s.trackHashtags(c, u.ShadowBanned || u.Protected)
+	}
+	return nil
+}
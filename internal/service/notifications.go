@@ -0,0 +1,286 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/stream"
+)
+
+// notificationTypes are the notification types users can mute
+var notificationTypes = []string{
+	db.NotifyMention,
+	db.NotifyReply,
+	db.NotifyLike,
+	db.NotifyRechirp,
+	db.NotifyQuote,
+	db.NotifyFollow,
+	db.NotifyFollowRequest,
+	db.NotifyFollowApproved,
+}
+
+// groupedTypes are the notification types that are grouped together when
+// they're about the same chirp (or, for follows, about the user)
+var groupedTypes = []string{db.NotifyLike, db.NotifyRechirp, db.NotifyFollow}
+
+// ResNotification holds a group of similar notifications to be used by
+// handlers in HTTP responses. Ungrouped notifications are groups of one.
+type ResNotification struct {
+	IDs      []int     `json:"ids"`
+	Type     string    `json:"type"`
+	ChirpID  int       `json:"chirp_id,omitempty"`
+	ActorIDs []int     `json:"actor_ids"`
+	Summary  string    `json:"summary"`
+	Note     string    `json:"note,omitempty"`
+	Read     bool      `json:"read"`
+	LatestAt time.Time `json:"latest_at"`
+}
+
+// ResNotifications holds a page of the user's notifications along with the
+// total number of unread notifications
+type ResNotifications struct {
+	UnreadCount   int               `json:"unread_count"`
+	Notifications []ResNotification `json:"notifications"`
+	HasMore       bool              `json:"has_more"`
+}
+
+// notify saves a notification for the recipient. Users aren't notified of
+// their own actions, of actions by users they blocked, were blocked by or
+// muted, or of actions by shadow-banned users. Failing to notify shouldn't
+// fail the action that caused it, so errors are only logged.
+func (s *Service) notify(recipientID int, actorID int, notifType string, chirpID int) {
+	if recipientID == actorID {
+		return
+	}
+
+	blocked, muted, err := s.relationsOf(recipientID)
+	if err != nil {
+		fmt.Println("Error getting user relations:", err)
+		return
+	}
+	if blocked[actorID] || muted[actorID] {
+		return
+	}
+
+	actor, err := s.dbConn.GetUser(actorID)
+	if err != nil || actor.ShadowBanned {
+		return
+	}
+
+	n, created, err := s.dbConn.CreateNotification(db.Notification{
+		UserID:  recipientID,
+		ActorID: actorID,
+		Type:    notifType,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		fmt.Println("Error creating notification:", err)
+		return
+	}
+
+	if created {
+		s.hub.Publish(stream.EventNotification, n, []int{recipientID})
+	}
+}
+
+// notifyModeration sends the user a notification from the moderators, which
+// can't be muted
+func (s *Service) notifyModeration(userID int, notifType string, note string) {
+	n, created, err := s.dbConn.CreateNotification(db.Notification{
+		UserID: userID,
+		Type:   notifType,
+		Note:   note,
+	})
+	if err != nil {
+		fmt.Println("Error creating notification:", err)
+		return
+	}
+
+	if created {
+		s.hub.Publish(stream.EventNotification, n, []int{userID})
+	}
+}
+
+// notifyChirp sends the notifications caused by a new chirp: mentions, and a
+// reply, quote or rechirp notification for the original chirp's author
+func (s *Service) notifyChirp(c db.Chirp, byID map[int]db.Chirp) {
+	notified := []int{}
+	if original, ok := byID[c.OriginalID]; ok {
+		switch c.Kind {
+		case db.KindReply:
+			s.notify(original.AuthorID, c.AuthorID, db.NotifyReply, c.ID)
+		case db.KindQuote:
+			s.notify(original.AuthorID, c.AuthorID, db.NotifyQuote, c.ID)
+		case db.KindRechirp:
+			s.notify(original.AuthorID, c.AuthorID, db.NotifyRechirp, original.ID)
+		}
+		notified = append(notified, original.AuthorID)
+	}
+
+	for _, m := range c.Mentions {
+		if slices.Contains(notified, m.UserID) {
+			continue
+		}
+		s.notify(m.UserID, c.AuthorID, db.NotifyMention, c.ID)
+		notified = append(notified, m.UserID)
+	}
+}
+
+// GetNotifications returns a page of the user's notifications, newest first,
+// with similar notifications grouped together. Notifications caused by users
+// the user blocked, was blocked by or muted are left out.
+func (s *Service) GetNotifications(userID int, page int, limit int) (ResNotifications, error) {
+	out := ResNotifications{Notifications: []ResNotification{}}
+
+	notifications, err := s.dbConn.GetNotifications(userID)
+	if err != nil {
+		return out, err
+	}
+	users, err := s.dbConn.GetUsers()
+	if err != nil {
+		return out, err
+	}
+
+	blocked, muted, err := s.relationsOf(userID)
+	if err != nil {
+		return out, err
+	}
+	notifications = slices.DeleteFunc(notifications, func(n db.Notification) bool {
+		return blocked[n.ActorID] || muted[n.ActorID]
+	})
+
+	slices.SortFunc(notifications, func(a, b db.Notification) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	groups := []ResNotification{}
+	groupIdx := map[string]int{}
+	for _, n := range notifications {
+		if !n.Read {
+			out.UnreadCount++
+		}
+
+		key := fmt.Sprint(n.ID)
+		if slices.Contains(groupedTypes, n.Type) {
+			key = fmt.Sprintf("%s:%d", n.Type, n.ChirpID)
+		}
+
+		// Notifications from the moderators have no actor
+		actorIDs := []int{n.ActorID}
+		if n.ActorID == 0 {
+			actorIDs = []int{}
+		}
+
+		i, ok := groupIdx[key]
+		if !ok {
+			groupIdx[key] = len(groups)
+			groups = append(groups, ResNotification{
+				IDs:      []int{n.ID},
+				Type:     n.Type,
+				ChirpID:  n.ChirpID,
+				ActorIDs: actorIDs,
+				Note:     n.Note,
+				Read:     n.Read,
+				LatestAt: n.CreatedAt,
+			})
+			continue
+		}
+
+		groups[i].IDs = append(groups[i].IDs, n.ID)
+		groups[i].Read = groups[i].Read && n.Read
+		if !slices.Contains(groups[i].ActorIDs, n.ActorID) {
+			groups[i].ActorIDs = append(groups[i].ActorIDs, n.ActorID)
+		}
+	}
+
+	start := min((page-1)*limit, len(groups))
+	end := min(start+limit, len(groups))
+	for _, g := range groups[start:end] {
+		g.Summary = summarize(g, users)
+		out.Notifications = append(out.Notifications, g)
+	}
+	out.HasMore = end < len(groups)
+
+	return out, nil
+}
+
+// summarize describes a notification group in words, e.g. "5 people liked
+// your chirp"
+func summarize(g ResNotification, users []db.User) string {
+	actor := fmt.Sprintf("%d people", len(g.ActorIDs))
+	if len(g.ActorIDs) == 1 {
+		actor = "Someone"
+		for _, u := range users {
+			if u.ID == g.ActorIDs[0] {
+				actor = "@" + u.Handle
+				break
+			}
+		}
+	}
+
+	switch g.Type {
+	case db.NotifyMention:
+		return actor + " mentioned you"
+	case db.NotifyReply:
+		return actor + " replied to your chirp"
+	case db.NotifyLike:
+		return actor + " liked your chirp"
+	case db.NotifyRechirp:
+		return actor + " rechirped your chirp"
+	case db.NotifyQuote:
+		return actor + " quoted your chirp"
+	case db.NotifyFollow:
+		return actor + " followed you"
+	case db.NotifyFollowRequest:
+		return actor + " requested to follow you"
+	case db.NotifyFollowApproved:
+		return actor + " approved your follow request"
+	case db.NotifyWarning:
+		return "The moderators sent you a warning"
+	case db.NotifyChirpRemoved:
+		return "The moderators removed your chirp"
+	}
+	return actor + " interacted with you"
+}
+
+// MarkNotificationsRead marks the given notifications of the user as read, or
+// all of them if no IDs are given
+func (s *Service) MarkNotificationsRead(userID int, ids []int) error {
+	return s.dbConn.MarkNotificationsRead(userID, ids)
+}
+
+// GetMutedNotifications returns the notification types muted by the user
+func (s *Service) GetMutedNotifications(userID int) ([]string, error) {
+	users, err := s.dbConn.GetUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range users {
+		if u.ID == userID {
+			if u.MutedNotifications == nil {
+				return []string{}, nil
+			}
+			return u.MutedNotifications, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// SetMutedNotifications replaces the notification types muted by the user
+func (s *Service) SetMutedNotifications(userID int, types []string) ([]string, error) {
+	muted := []string{}
+	for _, t := range types {
+		if !slices.Contains(notificationTypes, t) {
+			return nil, ErrInvalidNotificationType
+		}
+		if !slices.Contains(muted, t) {
+			muted = append(muted, t)
+		}
+	}
+
+	u, err := s.dbConn.SetMutedNotifications(userID, muted)
+	return u.MutedNotifications, err
+}
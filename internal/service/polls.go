@@ -0,0 +1,156 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/graphemes"
+)
+
+// Poll limits
+const (
+	minPollOptions   = 2
+	maxPollOptions   = 4
+	maxPollOptionLen = 25
+	maxPollDuration  = 7 * 24 * time.Hour
+)
+
+// NewPoll is a poll to attach to a new chirp
+type NewPoll struct {
+	Options   []string  `json:"options"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ResPollOption is a poll option along with its vote count
+type ResPollOption struct {
+	Text  string `json:"text"`
+	Votes int    `json:"votes"`
+}
+
+// ResPoll holds a chirp's poll and its tallies to be used by handlers in HTTP
+// responses. VotedOption is the option the viewer voted for, if any.
+type ResPoll struct {
+	ID          int             `json:"id"`
+	Options     []ResPollOption `json:"options"`
+	TotalVotes  int             `json:"total_votes"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+	Closed      bool            `json:"closed"`
+	VotedOption *int            `json:"voted_option,omitempty"`
+}
+
+// validatePoll trims the options of a new poll and checks that there are 2-4
+// distinct, non-empty options of up to 25 characters, and that it expires
+// within a week
+func validatePoll(p *NewPoll) error {
+	if len(p.Options) < minPollOptions || len(p.Options) > maxPollOptions {
+		return ErrInvalidPoll
+	}
+
+	seen := []string{}
+	for i, o := range p.Options {
+		o = strings.TrimSpace(o)
+		length := graphemes.Count(o)
+		if length == 0 || length > maxPollOptionLen || slices.Contains(seen, strings.ToLower(o)) {
+			return ErrInvalidPoll
+		}
+		seen = append(seen, strings.ToLower(o))
+		p.Options[i] = o
+	}
+
+	now := time.Now()
+	if !p.ExpiresAt.After(now) || p.ExpiresAt.After(now.Add(maxPollDuration)) {
+		return ErrInvalidPoll
+	}
+	return nil
+}
+
+// loadPolls returns every poll with its tallies, keyed by chirp ID. Votes are
+// counted live until the poll expires, after which the results are frozen.
+func (s *Service) loadPolls(viewerID int) (map[int]ResPoll, error) {
+	polls, err := s.dbConn.GetPolls()
+	if err != nil {
+		return nil, err
+	}
+	votes, err := s.dbConn.GetVotes()
+	if err != nil {
+		return nil, err
+	}
+	return pollsFor(polls, votes, viewerID), nil
+}
+
+// pollsFor is loadPolls over polls and votes that are already loaded
+func pollsFor(polls []db.Poll, votes []db.Vote, viewerID int) map[int]ResPoll {
+	byChirp := map[int]ResPoll{}
+	for _, p := range polls {
+		out := ResPoll{
+			ID:        p.ID,
+			Options:   []ResPollOption{},
+			ExpiresAt: p.ExpiresAt,
+			Closed:    !time.Now().Before(p.ExpiresAt),
+		}
+		for _, o := range p.Options {
+			out.Options = append(out.Options, ResPollOption{Text: o})
+		}
+
+		for _, v := range votes {
+			if v.PollID != p.ID || v.Option >= len(out.Options) || !v.CreatedAt.Before(p.ExpiresAt) {
+				continue
+			}
+			out.Options[v.Option].Votes++
+			out.TotalVotes++
+			if v.UserID == viewerID {
+				option := v.Option
+				out.VotedOption = &option
+			}
+		}
+		byChirp[p.ChirpID] = out
+	}
+	return byChirp
+}
+
+// Vote records the user's vote for an option (by index) of the poll of the
+// given chirp, returning the updated tallies. Each user can vote once, and
+// only until the poll expires.
+func (s *Service) Vote(userID int, chirpID string, option int) (ResPoll, error) {
+	cs, err := s.loadChirps(userID)
+	if err != nil {
+		return ResPoll{}, err
+	}
+
+	id, err := strconv.Atoi(chirpID)
+	if err != nil {
+		return ResPoll{}, ErrChirpNotFound
+	}
+	c, ok := cs.byID[id]
+	if !ok || !cs.visible(c) {
+		return ResPoll{}, ErrChirpNotFound
+	}
+	poll, ok := cs.polls[c.ID]
+	if !ok {
+		return ResPoll{}, ErrPollNotFound
+	}
+	if option < 0 || option >= len(poll.Options) {
+		return ResPoll{}, ErrInvalidVote
+	}
+
+	_, err = s.dbConn.CreateVote(poll.ID, userID, option)
+	if errors.Is(err, db.ErrAlreadyVoted) {
+		return ResPoll{}, ErrAlreadyVoted
+	}
+	if errors.Is(err, db.ErrPollClosed) {
+		return ResPoll{}, ErrPollClosed
+	}
+	if err != nil {
+		return ResPoll{}, err
+	}
+
+	polls, err := s.loadPolls(userID)
+	if err != nil {
+		return ResPoll{}, err
+	}
+	return polls[c.ID], nil
+}
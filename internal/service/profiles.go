@@ -0,0 +1,212 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/media"
+)
+
+// Profile limits
+const (
+	maxDisplayNameLen = 50
+	maxBioLen         = 160
+)
+
+// handlePattern matches valid handles: 3 to 15 letters, digits or
+// underscores
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,15}$`)
+
+// reservedHandles can't be taken by users since they could be mistaken for
+// the app itself or clash with routes
+var reservedHandles = []string{
+	"admin", "administrator", "api", "app", "avatars", "chirpy", "everyone",
+	"help", "here", "media", "me", "mod", "moderator", "null", "root",
+	"settings", "staff", "support", "system", "undefined",
+	// Routes under /api/users and the placeholder for deleted users
+	"avatar", "deleted", "export", "privacy", "profile",
+}
+
+// ResAuthor holds the compact public data of a user embedded into chirps and
+// other responses
+type ResAuthor struct {
+	ID          int    `json:"id"`
+	Handle      string `json:"handle"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	IsChirpyRed bool   `json:"is_chirpy_red"`
+	Protected   bool   `json:"protected"`
+}
+
+// ResProfile holds the public profile of a user
+type ResProfile struct {
+	ResAuthor
+	Bio            string `json:"bio"`
+	FollowerCount  int    `json:"follower_count"`
+	FollowingCount int    `json:"following_count"`
+	ChirpCount     int    `json:"chirp_count"`
+}
+
+func toResAuthor(u db.User) ResAuthor {
+	return ResAuthor{
+		ID:          u.ID,
+		Handle:      u.Handle,
+		DisplayName: u.DisplayName,
+		AvatarURL:   u.AvatarURL,
+		IsChirpyRed: u.IsChirpyRed,
+		Protected:   u.Protected,
+	}
+}
+
+// deletedAuthor is shown in place of a user whose account has been deleted
+func deletedAuthor(userID int) ResAuthor {
+	return ResAuthor{ID: userID, Handle: "deleted", DisplayName: "Deleted user"}
+}
+
+// validateHandle checks a handle's format and that it isn't reserved. Handles
+// can't be all digits so they're never confused with user IDs.
+func validateHandle(handle string) error {
+	if !handlePattern.MatchString(handle) {
+		return ErrInvalidHandle
+	}
+	if strings.Trim(handle, "0123456789") == "" {
+		return ErrInvalidHandle
+	}
+	if slices.Contains(reservedHandles, strings.ToLower(handle)) {
+		return ErrReservedHandle
+	}
+	return nil
+}
+
+// defaultHandle derives an available handle from the local part of an email
+// for users who didn't choose one
+func defaultHandle(email string, users []db.User) string {
+	local, _, _ := strings.Cut(email, "@")
+	base := strings.Map(func(r rune) rune {
+		if r == '_' || ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return -1
+	}, strings.ToLower(local))
+	base = base[:min(len(base), 10)]
+	if len(base) < 3 {
+		base = "user" + base
+	}
+
+	taken := func(h string) bool {
+		return validateHandle(h) != nil || slices.ContainsFunc(users, func(u db.User) bool {
+			return strings.EqualFold(u.Handle, h)
+		})
+	}
+
+	handle := base
+	for i := 1; taken(handle); i++ {
+		handle = fmt.Sprintf("%s%d", base, i)
+	}
+	return handle
+}
+
+// GetProfile returns the public profile of the user with the given handle
+// (case-insensitive)
+func (s *Service) GetProfile(handle string) (ResProfile, error) {
+	users, err := s.dbConn.GetUsers()
+	if err != nil {
+		return ResProfile{}, err
+	}
+
+	i := slices.IndexFunc(users, func(u db.User) bool { return strings.EqualFold(u.Handle, handle) })
+	if i == -1 {
+		return ResProfile{}, ErrUserNotFound
+	}
+	u := users[i]
+
+	profile := ResProfile{ResAuthor: toResAuthor(u), Bio: u.Bio}
+
+	follows, err := s.dbConn.GetFollows()
+	if err != nil {
+		return ResProfile{}, err
+	}
+	for _, f := range follows {
+		if f.FolloweeID == u.ID {
+			profile.FollowerCount++
+		}
+		if f.FollowerID == u.ID {
+			profile.FollowingCount++
+		}
+	}
+
+	chirps, err := s.dbConn.GetChirps()
+	if err != nil {
+		return ResProfile{}, err
+	}
+	for _, c := range chirps {
+		if c.AuthorID == u.ID {
+			profile.ChirpCount++
+		}
+	}
+
+	return profile, nil
+}
+
+// UpdateProfile validates and updates the handle, display name and bio of the
+// user with the given ID
+func (s *Service) UpdateProfile(userID int, handle string, displayName string, bio string) (ResProfile, error) {
+	err := validateHandle(handle)
+	if err != nil {
+		return ResProfile{}, err
+	}
+	if utf8.RuneCountInString(displayName) > maxDisplayNameLen || utf8.RuneCountInString(bio) > maxBioLen {
+		return ResProfile{}, ErrInvalidProfile
+	}
+
+	u, err := s.dbConn.UpdateProfile(userID, handle, strings.TrimSpace(displayName), strings.TrimSpace(bio))
+	if errors.Is(err, db.ErrHandleTaken) {
+		return ResProfile{}, ErrHandleTaken
+	}
+	if err != nil {
+		return ResProfile{}, err
+	}
+
+	s.indexUser(u)
+	return s.GetProfile(u.Handle)
+}
+
+// SetAvatar processes an uploaded avatar image for the user with the given ID
+// and stores its thumbnail, replacing any previous avatar
+func (s *Service) SetAvatar(userID int, image []byte) (ResAuthor, error) {
+	processed, err := media.Process(image)
+	if errors.Is(err, media.ErrUnsupportedImage) {
+		return ResAuthor{}, ErrInvalidImage
+	}
+	if err != nil {
+		return ResAuthor{}, err
+	}
+
+	// Thumbnails of JPEGs are JPEGs, and PNGs otherwise
+	ext := ".png"
+	if processed.Ext == ".jpg" {
+		ext = ".jpg"
+	}
+	key := fmt.Sprintf("avatars/%d%s", userID, ext)
+	err = s.blobs.Put(key, processed.Thumbnail)
+	if err != nil {
+		return ResAuthor{}, err
+	}
+
+	for _, oldExt := range []string{".jpg", ".png"} {
+		if oldExt != ext {
+			_ = s.blobs.Delete(fmt.Sprintf("avatars/%d%s", userID, oldExt))
+		}
+	}
+
+	// The version parameter keeps clients from showing a cached old avatar
+	avatarURL := fmt.Sprintf("%s?v=%d", s.blobs.URL(key), time.Now().Unix())
+	u, err := s.dbConn.SetAvatarURL(userID, avatarURL)
+	return toResAuthor(u), err
+}
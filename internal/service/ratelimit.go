@@ -0,0 +1,171 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/ratelimit"
+)
+
+// Rate limit policies, each of which can be overridden with an environment
+// variable of the form RATE_LIMIT_LOGIN=5/1m
+const (
+	LimitLogin  = "login"
+	LimitSignup = "signup"
+	LimitChirp  = "chirp"
+	LimitRead   = "read"
+)
+
+// defaultLimits are the policies used when no override is set
+var defaultLimits = map[string]ratelimit.Policy{
+	LimitLogin:  {Limit: 5, Window: time.Minute},
+	LimitSignup: {Limit: 5, Window: time.Hour},
+	LimitChirp:  {Limit: 10, Window: time.Minute},
+	LimitRead:   {Limit: 120, Window: time.Minute},
+}
+
+// InitRateLimits sets up a limiter for every policy. Chirpy Red users get
+// RATE_LIMIT_RED_MULTIPLIER (2 by default) times the usual limits. Client IPs
+// are taken from X-Forwarded-For only for requests coming from one of the
+// comma-separated IPs or CIDR ranges in TRUSTED_PROXIES.
+func (s *Service) InitRateLimits() {
+	s.limiters = map[string]*ratelimit.Limiter{}
+	for name, policy := range defaultLimits {
+		if env := os.Getenv("RATE_LIMIT_" + strings.ToUpper(name)); env != "" {
+			var err error
+			policy, err = ratelimit.ParsePolicy(env)
+			if err != nil {
+				panic(fmt.Errorf("RATE_LIMIT_%s: %w", strings.ToUpper(name), err))
+			}
+		}
+		s.limiters[name] = ratelimit.New(policy)
+	}
+
+	s.redLimitScale = envInt("RATE_LIMIT_RED_MULTIPLIER", 2)
+
+	s.trustedProxies = []netip.Prefix{}
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(p)
+			if addrErr != nil {
+				panic(fmt.Errorf("TRUSTED_PROXIES: %w", err))
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		s.trustedProxies = append(s.trustedProxies, prefix)
+	}
+}
+
+// isTrustedProxy reports whether the address belongs to a trusted proxy
+func (s *Service) isTrustedProxy(addr string) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	return slices.ContainsFunc(s.trustedProxies, func(p netip.Prefix) bool { return p.Contains(ip) })
+}
+
+// ClientIP returns the IP address a request came from. Requests from trusted
+// proxies are attributed to the last address in X-Forwarded-For that isn't a
+// trusted proxy itself; everyone else could put anything in that header, so
+// for them it is ignored.
+func (s *Service) ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !s.isTrustedProxy(ip) {
+		return ip
+	}
+
+	forwarded := []string{}
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(h, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break
+		}
+		ip = hop
+		if !s.isTrustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// rateLimitKey returns the bucket key of a request and how much its limits
+// are scaled. Signed in users are limited per account, with higher limits for
+// Chirpy Red users, and everyone else per IP.
+func (s *Service) rateLimitKey(r *http.Request) (string, int) {
+	bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+	userID, err := s.AuthorizeUser(bearer)
+	if err != nil {
+		return "ip:" + s.ClientIP(r), 1
+	}
+
+	u, err := s.dbConn.GetUser(userID)
+	if err == nil && u.IsChirpyRed {
+		return "user:" + strconv.Itoa(userID), s.redLimitScale
+	}
+	return "user:" + strconv.Itoa(userID), 1
+}
+
+// MiddlewareRateLimit limits requests according to the named policy. Every
+// response carries the RateLimit-* headers, and requests over the limit get
+// a 429 response with Retry-After.
+func (s *Service) MiddlewareRateLimit(policy string) func(http.Handler) http.Handler {
+	limiter := s.limiters[policy]
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, scale := s.rateLimitKey(r)
+			res := limiter.Allow(key, scale)
+
+			w.Header().Set("RateLimit-Policy", ratelimit.Policy{
+				Limit:  res.Limit,
+				Window: limiter.Policy().Window,
+			}.String())
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+			if !res.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MiddlewareRateLimitReads limits GET and HEAD requests according to the
+// read policy and lets other requests through
+func (s *Service) MiddlewareRateLimitReads(next http.Handler) http.Handler {
+	limited := s.MiddlewareRateLimit(LimitRead)(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			limited.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/graphemes"
+)
+
+// maxReportReasonLen is the maximum length of a report's reason, and of the
+// note a moderator adds to an action
+const maxReportReasonLen = 500
+
+// ResReport holds a report along with the users involved and the actions
+// taken on it, to be used by handlers in HTTP responses
+type ResReport struct {
+	ID        int            `json:"id"`
+	Reporter  ResAuthor      `json:"reporter"`
+	User      ResAuthor      `json:"user"`
+	ChirpID   int            `json:"chirp_id,omitempty"`
+	ChirpBody string         `json:"chirp_body,omitempty"`
+	Reason    string         `json:"reason"`
+	Status    string         `json:"status"`
+	CreatedAt time.Time      `json:"created_at"`
+	ClosedAt  *time.Time     `json:"closed_at,omitempty"`
+	Actions   []db.ModAction `json:"actions"`
+}
+
+// ReportChirp reports a chirp the reporter can see to the moderators
+func (s *Service) ReportChirp(reporterID int, chirpID int, reason string) (db.Report, error) {
+	c, ok := s.GetChirp(reporterID, strconv.Itoa(chirpID))
+	if !ok {
+		return db.Report{}, ErrChirpNotFound
+	}
+	return s.report(db.Report{
+		ReporterID: reporterID,
+		UserID:     c.AuthorID,
+		ChirpID:    c.ID,
+		ChirpBody:  c.Body,
+		Reason:     reason,
+	})
+}
+
+// ReportUser reports an account to the moderators
+func (s *Service) ReportUser(reporterID int, userID int, reason string) (db.Report, error) {
+	_, err := s.dbConn.GetUser(userID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return db.Report{}, ErrUserNotFound
+	}
+	if err != nil {
+		return db.Report{}, err
+	}
+	return s.report(db.Report{ReporterID: reporterID, UserID: userID, Reason: reason})
+}
+
+// report saves a new report after checking its reason. Users can't report
+// themselves or their own chirps, or report the same thing twice while the
+// first report is still open.
+func (s *Service) report(r db.Report) (db.Report, error) {
+	r.Reason = strings.TrimSpace(r.Reason)
+	reasonLen := graphemes.Count(r.Reason)
+	if reasonLen == 0 || reasonLen > maxReportReasonLen || r.ReporterID == r.UserID {
+		return db.Report{}, ErrInvalidReport
+	}
+
+	r, created, err := s.dbConn.CreateReport(r)
+	if err != nil {
+		return r, err
+	}
+	if !created {
+		return r, ErrAlreadyReported
+	}
+	return r, nil
+}
+
+// GetReports returns the reports with the given status, or all reports if no
+// status is given, oldest first, to a moderator
+func (s *Service) GetReports(moderatorID int, status string) ([]ResReport, error) {
+	out := []ResReport{}
+	err := s.RequireRole(moderatorID, db.RoleModerator)
+	if err != nil {
+		return out, err
+	}
+
+	switch status {
+	case "", db.ReportOpen, db.ReportResolved, db.ReportDismissed:
+	default:
+		return out, ErrInvalidReportStatus
+	}
+
+	reports, err := s.dbConn.GetReports()
+	if err != nil {
+		return out, err
+	}
+	slices.SortFunc(reports, func(a, b db.Report) int { return a.ID - b.ID })
+
+	for _, r := range reports {
+		if status != "" && r.Status != status {
+			continue
+		}
+		res, err := s.toResReport(r)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// GetReport returns the report of the given ID to a moderator
+func (s *Service) GetReport(moderatorID int, reportID int) (ResReport, error) {
+	err := s.RequireRole(moderatorID, db.RoleModerator)
+	if err != nil {
+		return ResReport{}, err
+	}
+
+	r, err := s.reportByID(reportID)
+	if err != nil {
+		return ResReport{}, err
+	}
+	return s.toResReport(r)
+}
+
+func (s *Service) reportByID(reportID int) (db.Report, error) {
+	reports, err := s.dbConn.GetReports()
+	if err != nil {
+		return db.Report{}, err
+	}
+	i := slices.IndexFunc(reports, func(r db.Report) bool { return r.ID == reportID })
+	if i == -1 {
+		return db.Report{}, ErrReportNotFound
+	}
+	return reports[i], nil
+}
+
+// toResReport renders a report along with the users involved and the actions
+// taken on it
+func (s *Service) toResReport(r db.Report) (ResReport, error) {
+	reporter, err := s.reportAuthor(r.ReporterID)
+	if err != nil {
+		return ResReport{}, err
+	}
+	user, err := s.reportAuthor(r.UserID)
+	if err != nil {
+		return ResReport{}, err
+	}
+
+	actions, err := s.dbConn.GetModActions()
+	if err != nil {
+		return ResReport{}, err
+	}
+	actions = slices.DeleteFunc(actions, func(a db.ModAction) bool { return a.ReportID != r.ID })
+	slices.SortFunc(actions, func(a, b db.ModAction) int { return a.ID - b.ID })
+
+	return ResReport{
+		ID:        r.ID,
+		Reporter:  reporter,
+		User:      user,
+		ChirpID:   r.ChirpID,
+		ChirpBody: r.ChirpBody,
+		Reason:    r.Reason,
+		Status:    r.Status,
+		CreatedAt: r.CreatedAt,
+		ClosedAt:  r.ClosedAt,
+		Actions:   actions,
+	}, nil
+}
+
+// reportAuthor returns the public data of a user involved in a report, which
+// is kept after either user deletes their account
+func (s *Service) reportAuthor(userID int) (ResAuthor, error) {
+	u, err := s.dbConn.GetUser(userID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return deletedAuthor(userID), nil
+	}
+	if err != nil {
+		return ResAuthor{}, err
+	}
+	return toResAuthor(u), nil
+}
+
+// Moderate takes an action on an open report and closes it:
+//   - remove deletes the reported chirp, closing every open report of it, and
+//     tells its author
+//   - warn sends the reported user a warning with the note
+//   - suspend suspends the reported user, for the given duration or
+//     indefinitely if it's 0, with the note as the reason
+//   - dismiss closes the report without taking action
+//
+// Actions are audited along with the IP they came from.
+func (s *Service) Moderate(moderatorID int, reportID int, action string, note string, duration time.Duration, ip string) (ResReport, error) {
+	err := s.RequireRole(moderatorID, db.RoleModerator)
+	if err != nil {
+		return ResReport{}, err
+	}
+
+	r, err := s.reportByID(reportID)
+	if err != nil {
+		return ResReport{}, err
+	}
+	if r.Status != db.ReportOpen {
+		return ResReport{}, ErrReportClosed
+	}
+
+	note = strings.TrimSpace(note)
+	if graphemes.Count(note) > maxReportReasonLen || duration < 0 {
+		return ResReport{}, ErrInvalidModAction
+	}
+
+	a := db.ModAction{
+		ReportID:    r.ID,
+		ModeratorID: moderatorID,
+		Action:      action,
+		UserID:      r.UserID,
+		ChirpID:     r.ChirpID,
+		Note:        note,
+	}
+	status := db.ReportResolved
+	closeIDs := []int{}
+
+	switch action {
+	case db.ModRemove:
+		if r.ChirpID == 0 {
+			return ResReport{}, ErrInvalidModAction
+		}
+		closeIDs, err = s.openReportsOf(r.ChirpID)
+		if err != nil {
+			return ResReport{}, err
+		}
+	case db.ModSuspend:
+		_, err = s.moderatable(r.UserID)
+		if err != nil {
+			return ResReport{}, err
+		}
+		if duration > 0 {
+			until := time.Now().Add(duration)
+			a.Until = &until
+		}
+	case db.ModWarn:
+	case db.ModDismiss:
+		status = db.ReportDismissed
+	default:
+		return ResReport{}, ErrInvalidModAction
+	}
+
+	a, err = s.dbConn.CreateModAction(a, status, closeIDs)
+	if errors.Is(err, db.ErrReportClosed) {
+		return ResReport{}, ErrReportClosed
+	}
+	if err != nil {
+		return ResReport{}, err
+	}
+	s.recordModAction(a, ip)
+
+	err = s.applyModAction(a)
+	if err != nil {
+		return ResReport{}, err
+	}
+	return s.GetReport(moderatorID, r.ID)
+}
+
+// openReportsOf returns the IDs of the open reports of the given chirp
+func (s *Service) openReportsOf(chirpID int) ([]int, error) {
+	reports, err := s.dbConn.GetReports()
+	if err != nil {
+		return nil, err
+	}
+	ids := []int{}
+	for _, r := range reports {
+		if r.ChirpID == chirpID && r.Status == db.ReportOpen {
+			ids = append(ids, r.ID)
+		}
+	}
+	return ids, nil
+}
+
+// applyModAction carries out a recorded moderation action. A chirp that's
+// already been deleted by its author doesn't need removing.
+func (s *Service) applyModAction(a db.ModAction) error {
+	switch a.Action {
+	case db.ModRemove:
+		_, err := s.removeChirp(strconv.Itoa(a.ChirpID))
+		if errors.Is(err, ErrChirpNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.notifyModeration(a.UserID, db.NotifyChirpRemoved, a.Note)
+	case db.ModWarn:
+		s.notifyModeration(a.UserID, db.NotifyWarning, a.Note)
+	case db.ModSuspend:
+		_, err := s.dbConn.SuspendUser(a.UserID, a.Until, a.Note)
+		if err != nil {
+			return err
+		}
+		s.hub.Disconnect(a.UserID)
+	case db.ModUnsuspend:
+		_, err := s.dbConn.UnsuspendUser(a.UserID)
+		return err
+	case db.ModShadowBan, db.ModUnshadowBan:
+		u, err := s.dbConn.SetShadowBanned(a.UserID, a.Action == db.ModShadowBan)
+		if err != nil {
+			return err
+		}
+		return s.retrackChirps(u)
+	}
+	return nil
+}
+
+// GetModActions returns every moderation action taken, newest first, to a
+// moderator
+func (s *Service) GetModActions(moderatorID int) ([]db.ModAction, error) {
+	err := s.RequireRole(moderatorID, db.RoleModerator)
+	if err != nil {
+		return nil, err
+	}
+
+	actions, err := s.dbConn.GetModActions()
+	if err != nil {
+		return actions, err
+	}
+	slices.SortFunc(actions, func(a, b db.ModAction) int { return b.ID - a.ID })
+	return actions, nil
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// roles lists the user roles from least to most privileged. Each role has
+// the permissions of the ones before it.
+var roles = []string{db.RoleUser, db.RoleModerator, db.RoleAdmin}
+
+// roleOf returns the user's role, treating users without one as regular users
+func roleOf(u db.User) string {
+	if u.Role == "" {
+		return db.RoleUser
+	}
+	return u.Role
+}
+
+// hasRole reports whether the user has the given role or a more privileged
+// one
+func hasRole(u db.User, role string) bool {
+	return slices.Index(roles, roleOf(u)) >= slices.Index(roles, role)
+}
+
+// RequireRole returns ErrForbidden unless the user has the given role or a
+// more privileged one
+func (s *Service) RequireRole(userID int, role string) error {
+	u, err := s.dbConn.GetUser(userID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	if !hasRole(u, role) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// MiddlewareRequireRole only lets requests through if their bearer token
+// belongs to a user with the given role or a more privileged one, responding
+// with 401 to requests without a valid token and 403 to the rest
+func (s *Service) MiddlewareRequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bearer := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+			userID, err := s.AuthorizeUser(bearer)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			err = s.RequireRole(userID, role)
+			if errors.Is(err, ErrForbidden) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SetRole changes the role of the user with the given ID. Admins can't change
+// their own role, so there's always at least one admin left.
+func (s *Service) SetRole(adminID int, userID int, role string, ip string) (ResUserData, error) {
+	err := s.RequireRole(adminID, db.RoleAdmin)
+	if err != nil {
+		return ResUserData{}, err
+	}
+
+	if !slices.Contains(roles, role) {
+		return ResUserData{}, ErrInvalidRole
+	}
+	if adminID == userID {
+		return ResUserData{}, ErrForbidden
+	}
+
+	u, err := s.dbConn.SetRole(userID, role)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return ResUserData{}, ErrUserNotFound
+	}
+	if err != nil {
+		return ResUserData{}, err
+	}
+	s.record(AuditRoleChanged, adminID, ip, userTarget(userID), map[string]string{"role": role})
+
+	return ResUserData{
+		ID:             u.ID,
+		Email:          u.Email,
+		Handle:         u.Handle,
+		IsChirpyRed:    u.IsChirpyRed,
+		MaxChirpLength: s.maxChirpLength(u.IsChirpyRed),
+		Role:           roleOf(u),
+	}, nil
+}
+
+// BootstrapAdmin makes the first admin, creating the user if there's none
+// with the given email yet. An existing user's password has to match. Once
+// there's an admin, further admins are made through the admin API instead.
+func (s *Service) BootstrapAdmin(email string, password string) (db.User, error) {
+	email = strings.TrimSpace(email)
+	users, err := s.dbConn.GetUsers()
+	if err != nil {
+		return db.User{}, err
+	}
+	if slices.ContainsFunc(users, func(u db.User) bool { return u.Role == db.RoleAdmin }) {
+		return db.User{}, ErrAdminExists
+	}
+
+	i := slices.IndexFunc(users, func(u db.User) bool { return strings.EqualFold(u.Email, email) })
+	var u db.User
+	if i == -1 {
+		u, err = s.CreateUser(email, password, "")
+		if err != nil {
+			return db.User{}, err
+		}
+	} else {
+		u = users[i]
+		err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
+		if err != nil {
+			return db.User{}, ErrWrongPassword
+		}
+	}
+
+	u, err = s.dbConn.SetRole(u.ID, db.RoleAdmin)
+	if err != nil {
+		return u, err
+	}
+	s.record(AuditRoleChanged, 0, "", userTarget(u.ID), map[string]string{
+		"role": db.RoleAdmin,
+		"via":  "create-admin",
+	})
+	return u, nil
+}
@@ -4,8 +4,10 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"os"
 	"slices"
 	"strconv"
@@ -13,15 +15,104 @@ import (
 	"time"
 
 	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/wipdev-tech/chirpy/internal/audit"
 	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/filter"
+	"github.com/wipdev-tech/chirpy/internal/media"
+	"github.com/wipdev-tech/chirpy/internal/ratelimit"
+	"github.com/wipdev-tech/chirpy/internal/search"
+	"github.com/wipdev-tech/chirpy/internal/spam"
+	"github.com/wipdev-tech/chirpy/internal/stream"
+	"github.com/wipdev-tech/chirpy/internal/trends"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Errors returned by the service that handlers map to specific HTTP statuses
+var (
+	ErrChirpNotFound           = errors.New("chirp doesn't exist")
+	ErrAlreadyRechirped        = errors.New("chirp already rechirped")
+	ErrUserNotFound            = errors.New("user doesn't exist")
+	ErrSelfFollow              = errors.New("users can't follow themselves")
+	ErrInvalidNotificationType = errors.New("invalid notification type")
+	ErrInvalidParticipants     = errors.New("invalid conversation participants")
+	ErrDMNotAllowed            = errors.New("user doesn't accept messages from you")
+	ErrConversationNotFound    = errors.New("conversation doesn't exist")
+	ErrInvalidHandle           = errors.New("handles must be 3-15 letters, digits or underscores")
+	ErrReservedHandle          = errors.New("handle is reserved")
+	ErrHandleTaken             = errors.New("handle already taken")
+	ErrInvalidProfile          = errors.New("display name or bio too long")
+	ErrInvalidImage            = errors.New("unsupported image type")
+	ErrMediaNotFound           = errors.New("media not found")
+	ErrInvalidMedia            = errors.New("invalid media attachments")
+	ErrSelfBlock               = errors.New("users can't block or mute themselves")
+	ErrBlocked                 = errors.New("user is blocked")
+	ErrRejectedContent         = errors.New("chirp contains banned words")
+	ErrInvalidFilterRule       = errors.New("invalid filter rule")
+	ErrFilterRuleNotFound      = errors.New("filter rule doesn't exist")
+	ErrChirpTooLong            = errors.New("chirp is too long")
+	ErrNotAuthor               = errors.New("user isn't the chirp's author")
+	ErrNotEditable             = errors.New("rechirps can't be edited")
+	ErrEditWindowClosed        = errors.New("chirp can no longer be edited")
+	ErrDraftNotFound           = errors.New("draft doesn't exist")
+	ErrInvalidPublishTime      = errors.New("publish time must be in the future")
+	ErrInvalidPoll             = errors.New("polls need 2-4 distinct options and to expire within a week")
+	ErrPollNotFound            = errors.New("chirp has no poll")
+	ErrInvalidVote             = errors.New("invalid poll option")
+	ErrAlreadyVoted            = errors.New("user already voted")
+	ErrPollClosed              = errors.New("poll has expired")
+	ErrInvalidList             = errors.New("list name or description too long")
+	ErrListNotFound            = errors.New("list doesn't exist")
+	ErrNotListOwner            = errors.New("user doesn't own the list")
+	ErrInvalidVisibility       = errors.New("invalid chirp visibility")
+	ErrNotRechirpable          = errors.New("only public chirps can be rechirped")
+	ErrFollowRequestNotFound   = errors.New("follow request doesn't exist")
+	ErrInvalidReport           = errors.New("invalid report")
+	ErrAlreadyReported         = errors.New("already reported")
+	ErrReportNotFound          = errors.New("report doesn't exist")
+	ErrInvalidReportStatus     = errors.New("invalid report status")
+	ErrReportClosed            = errors.New("report already closed")
+	ErrInvalidModAction        = errors.New("invalid moderation action")
+	ErrSuspended               = errors.New("account is suspended")
+	ErrForbidden               = errors.New("user doesn't have the required role")
+	ErrInvalidRole             = errors.New("invalid role")
+	ErrSpam                    = errors.New("chirp looks like spam")
+	ErrInvalidVerdict          = errors.New("invalid spam verdict")
+	ErrVerdictNotFound         = errors.New("spam verdict doesn't exist")
+	ErrInvalidSpamReview       = errors.New("only held chirps can be approved or removed")
+	ErrVerdictReviewed         = errors.New("spam verdict already reviewed")
+	ErrEmailTaken              = errors.New("email already taken")
+	ErrInvalidEmail            = errors.New("invalid email")
+	ErrWeakPassword            = errors.New("password doesn't meet the password policy")
+	ErrSessionRevoked          = errors.New("session has been revoked")
+	ErrAdminExists             = errors.New("there already is an admin")
+	ErrWrongPassword           = errors.New("wrong password")
+	ErrNotSuspended            = errors.New("user isn't suspended")
+)
+
 // ResUserData holds user data to be used by handlers in HTTP responses
 type ResUserData struct {
-	ID          int    `json:"id"`
-	Email       string `json:"email"`
-	IsChirpyRed bool   `json:"is_chirpy_red"`
+	ID             int    `json:"id"`
+	Email          string `json:"email"`
+	Handle         string `json:"handle"`
+	IsChirpyRed    bool   `json:"is_chirpy_red"`
+	MaxChirpLength int    `json:"max_chirp_length"`
+	Role           string `json:"role"`
+}
+
+// ResUserUpdate embeds ResUserData with the new access and refresh JWTs
+// issued after a password change, which revokes all the user's other tokens
+type ResUserUpdate struct {
+	ResUserData
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// UserUpdate holds the account fields to change. Nil fields are left as they
+// are, and CurrentPassword is needed to change either of the others.
+type UserUpdate struct {
+	Email           *string
+	Password        *string
+	CurrentPassword string
 }
 
 // ResUserDataT embeds resUserData with the addition of access and refresh JWTS
@@ -31,19 +122,54 @@ type ResUserDataT struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// ResSearch holds the chirps and users matching a search query
+type ResSearch struct {
+	Chirps []ResChirp  `json:"chirps"`
+	Users  []ResAuthor `json:"users"`
+}
+
 // ResRefresh holds only a new access JWT generated after a successful refresh
 type ResRefresh struct {
 	Token string `json:"token"`
 }
 
-// Service contains the app data (right now it's only the server hits and DB
-// connection), middleware functions, business logic, and calls to the DB.
+// ResChirp holds chirp data to be used by handlers in HTTP responses. For
+// rechirps, quotes and replies, the original chirp is embedded. If the original has
+// been deleted, OriginalDeleted is set instead. Mention and hashtag offsets are
+// in grapheme clusters, like the chirp length.
+type ResChirp struct {
+	db.Chirp
+	Author          *ResAuthor `json:"author"`
+	Media           []ResMedia `json:"media,omitempty"`
+	Poll            *ResPoll   `json:"poll,omitempty"`
+	Original        *ResChirp  `json:"original,omitempty"`
+	OriginalDeleted bool       `json:"original_deleted,omitempty"`
+	// OriginalUnavailable is set when the viewer can't see the original,
+	// because of a block or the original's visibility
+	OriginalUnavailable bool `json:"original_unavailable,omitempty"`
+}
+
+// Service contains the app data (server hits, DB connection, in-memory trends,
+// search index, streaming hub and blob store), middleware functions, business logic, and calls to the DB.
 type Service struct {
 	FileserverHits int
 	dbConn         *db.DB
+	trends         *trends.Tracker
+	index          *search.Index
+	hub            *stream.Hub
+	blobs          media.BlobStore
+	filter         *filter.Filter
+	maxChirpLen    int
+	maxChirpLenRed int
+	editWindow     time.Duration
+	audit          *audit.Log
+	limiters       map[string]*ratelimit.Limiter
+	redLimitScale  int
+	trustedProxies []netip.Prefix
+	spam           *spam.Scorer
 }
 
-func sortChirpsAsc(a, b db.Chirp) int {
+func sortChirpsAsc(a, b ResChirp) int {
 	if a.ID < b.ID {
 		return -1
 	}
@@ -55,7 +181,7 @@ func sortChirpsAsc(a, b db.Chirp) int {
 	return 0
 }
 
-func sortChirpsDesc(a, b db.Chirp) int {
+func sortChirpsDesc(a, b ResChirp) int {
 	if a.ID < b.ID {
 		return 1
 	}
@@ -85,7 +211,7 @@ func (s *Service) MiddlewareMetricsInc(next http.Handler) http.Handler {
 func (s *Service) MiddlewareCors(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, DELETE")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, PATCH, DELETE")
 		w.Header().Set("Access-Control-Allow-Headers", "*")
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -105,28 +231,306 @@ func (s *Service) InitDB() {
 	s.dbConn = newDB
 }
 
-// GetChirp queries the database a chirp by its ID. It returns a chirp and
-// boolean indicating whether the chirp was found (to be used in a comma-ok
-// idiom).
-func (s *Service) GetChirp(chirpID string) (db.Chirp, bool) {
+// chirpSet holds all chirps keyed by ID along with their authors, media and
+// polls, which is everything needed to render chirps for responses. It also
+// holds the viewer the chirps are rendered for, along with the users the
+// viewer follows, blocked or muted, and which accounts are protected.
+type chirpSet struct {
+	byID      map[int]db.Chirp
+	authors   map[int]ResAuthor
+	media     map[int]ResMedia
+	polls     map[int]ResPoll
+	viewerID  int
+	following map[int]bool
+	protected map[int]bool
+	// shadowBanned authors' chirps are only visible to themselves
+	shadowBanned map[int]bool
+	blocked      map[int]bool
+	muted        map[int]bool
+}
+
+// loadChirps queries the database for all chirps, users, media and polls,
+// returning them in a chirp set for the given viewer (0 for anonymous viewers)
+func (s *Service) loadChirps(viewerID int) (chirpSet, error) {
+	cs := chirpSet{
+		byID:         map[int]db.Chirp{},
+		authors:      map[int]ResAuthor{},
+		media:        map[int]ResMedia{},
+		viewerID:     viewerID,
+		following:    map[int]bool{},
+		protected:    map[int]bool{},
+		shadowBanned: map[int]bool{},
+	}
+
+	var err error
+	cs.blocked, cs.muted, err = s.relationsOf(viewerID)
+	if err != nil {
+		return cs, err
+	}
+
+	chirps, err := s.dbConn.GetChirps()
+	if err != nil {
+		return cs, err
+	}
+	for _, c := range chirps {
+		cs.byID[c.ID] = c
+	}
+
+	users, err := s.dbConn.GetUsers()
+	if err != nil {
+		return cs, err
+	}
+	for _, u := range users {
+		cs.authors[u.ID] = toResAuthor(u)
+		cs.protected[u.ID] = u.Protected
+		cs.shadowBanned[u.ID] = u.ShadowBanned
+	}
+
+	follows, err := s.dbConn.GetFollows()
+	if err != nil {
+		return cs, err
+	}
+	for _, f := range follows {
+		if f.FollowerID == viewerID {
+			cs.following[f.FolloweeID] = true
+		}
+	}
+
+	mediaList, err := s.dbConn.GetMedia()
+	if err != nil {
+		return cs, err
+	}
+	for _, m := range mediaList {
+		cs.media[m.ID] = s.toResMedia(m)
+	}
+
+	cs.polls, err = s.loadPolls(viewerID)
+	if err != nil {
+		return cs, err
+	}
+
+	return cs, nil
+}
+
+// visible reports whether the viewer can see a chirp at all, which isn't the
+// case for chirps by users blocked either way, chirps the visibility of which
+// excludes the viewer, or rechirps of such chirps
+func (cs chirpSet) visible(c db.Chirp) bool {
+	if !cs.canSee(c) {
+		return false
+	}
+	if c.Kind == db.KindRechirp {
+		return cs.canSee(cs.byID[c.OriginalID])
+	}
+	return true
+}
+
+// canSee checks the blocks, shadow bans and visibility of a single chirp for
+// the viewer
+func (cs chirpSet) canSee(c db.Chirp) bool {
+	if cs.shadowBanned[c.AuthorID] && c.AuthorID != cs.viewerID {
+		return false
+	}
+	return !cs.blocked[c.AuthorID] && canSee(c, cs.viewerID, cs.following[c.AuthorID], cs.protected[c.AuthorID])
+}
+
+// inTimeline reports whether a chirp should be listed for the viewer. On top
+// of being visible, chirps by muted users (and rechirps of them) are hidden.
+func (cs chirpSet) inTimeline(c db.Chirp) bool {
+	if !cs.visible(c) || cs.muted[c.AuthorID] {
+		return false
+	}
+	if c.Kind == db.KindRechirp {
+		return !cs.muted[cs.byID[c.OriginalID].AuthorID]
+	}
+	return true
+}
+
+// render embeds the author, media and poll into a chirp, and the original
+// chirp into rechirps, quotes and replies. Originals are embedded one level
+// deep only, and originals the viewer can't see are left out.
+func (cs chirpSet) render(c db.Chirp) ResChirp {
+	out := cs.renderShallow(c)
+	if c.OriginalID == 0 {
+		return out
+	}
+
+	original, ok := cs.byID[c.OriginalID]
+	switch {
+	case !ok:
+		out.OriginalDeleted = true
+	case !cs.canSee(original):
+		out.OriginalUnavailable = true
+	default:
+		resOriginal := cs.renderShallow(original)
+		out.Original = &resOriginal
+	}
+	return out
+}
+
+// renderShallow embeds the author, media and poll into a chirp, without its
+// original
+func (cs chirpSet) renderShallow(c db.Chirp) ResChirp {
+	out := ResChirp{Chirp: c}
+	if author, ok := cs.authors[c.AuthorID]; ok {
+		out.Author = &author
+	}
+	for _, id := range c.MediaIDs {
+		if m, ok := cs.media[id]; ok {
+			out.Media = append(out.Media, m)
+		}
+	}
+	if poll, ok := cs.polls[c.ID]; ok {
+		out.Poll = &poll
+	}
+	return out
+}
+
+// InitTrends creates the trends tracker and seeds it with the hashtags of
+// recent chirps. The ranking refresh interval is read from the TRENDS_REFRESH
+// environment variable (e.g. "30s") and defaults to one minute.
+func (s *Service) InitTrends() {
+	refresh := time.Minute
+	if env := os.Getenv("TRENDS_REFRESH"); env != "" {
+		d, err := time.ParseDuration(env)
+		if err != nil {
+			panic(err)
+		}
+		refresh = d
+	}
+	s.trends = trends.NewTracker(refresh)
+
+	users, err := s.dbConn.GetUsers()
+	if err != nil {
+		panic(err)
+	}
+	hidden := map[int]bool{}
+	for _, u := range users {
+		hidden[u.ID] = u.ShadowBanned || u.Protected
+	}
+
 	chirps, err := s.dbConn.GetChirps()
 	if err != nil {
 		panic(err)
 	}
 	for _, c := range chirps {
-		if fmt.Sprintf("%d", c.ID) == chirpID {
-			return c, true
+		s.trackHashtags(c, hidden[c.AuthorID])
+	}
+}
+
+// trackHashtags registers the hashtags of a new chirp in the trends tracker.
+// Only public chirps that aren't held count towards trends, and only if their
+// author isn't hidden from trends by being shadow-banned or having a
+// protected account.
+func (s *Service) trackHashtags(c db.Chirp, authorHidden bool) {
+	if (c.Visibility != "" && c.Visibility != db.VisibilityPublic) || c.Held || authorHidden {
+		return
+	}
+
+	tags := []string{}
+	for _, h := range c.Hashtags {
+		if !slices.Contains(tags, h.Tag) {
+			tags = append(tags, h.Tag)
 		}
 	}
-	return db.Chirp{}, false
+	s.trends.Add(c.ID, tags, c.CreatedAt)
 }
 
-// GetChirps queries the database for all chirps, returning them in a slice.
-func (s *Service) GetChirps(sortAsc bool) []db.Chirp {
+// GetTrends returns the top hashtags for every trends window
+func (s *Service) GetTrends(limit int) map[string][]trends.Trend {
+	return s.trends.Top(limit)
+}
+
+// InitSearch builds the search index from the chirps and users currently in
+// the database
+func (s *Service) InitSearch() {
+	s.index = search.NewIndex()
+
 	chirps, err := s.dbConn.GetChirps()
 	if err != nil {
 		panic(err)
 	}
+	for _, c := range chirps {
+		s.indexChirp(c)
+	}
+
+	users, err := s.dbConn.GetUsers()
+	if err != nil {
+		panic(err)
+	}
+	for _, u := range users {
+		s.indexUser(u)
+	}
+}
+
+// indexChirp adds a chirp to the search index. Rechirps have no text of their
+// own so they are not indexed.
+func (s *Service) indexChirp(c db.Chirp) {
+	if c.Kind == db.KindRechirp {
+		return
+	}
+	s.index.AddChirp(c.ID, c.AuthorID, c.Body, c.CreatedAt)
+}
+
+// indexUser adds a user's public profile to the search index
+func (s *Service) indexUser(u db.User) {
+	s.index.AddUser(u.ID, strings.Join([]string{u.Handle, u.DisplayName, u.Bio}, " "))
+}
+
+// Search looks up the chirps matching the query along with the users matching
+// the query text, hiding the ones blocked or muted by the viewer
+func (s *Service) Search(viewerID int, q search.Query) (ResSearch, error) {
+	out := ResSearch{Chirps: []ResChirp{}, Users: []ResAuthor{}}
+
+	cs, err := s.loadChirps(viewerID)
+	if err != nil {
+		return out, err
+	}
+	for _, id := range s.index.SearchChirps(q) {
+		if c, ok := cs.byID[id]; ok && cs.inTimeline(c) {
+			out.Chirps = append(out.Chirps, cs.render(c))
+		}
+	}
+
+	for _, id := range s.index.SearchUsers(q.Text) {
+		if author, ok := cs.authors[id]; ok && !cs.blocked[id] && !cs.muted[id] {
+			out.Users = append(out.Users, author)
+		}
+	}
+
+	return out, nil
+}
+
+// GetChirp queries the database a chirp by its ID. It returns a chirp and
+// boolean indicating whether the chirp was found (to be used in a comma-ok
+// idiom). Chirps the viewer can't see are reported as not found.
+func (s *Service) GetChirp(viewerID int, chirpID string) (ResChirp, bool) {
+	cs, err := s.loadChirps(viewerID)
+	if err != nil {
+		panic(err)
+	}
+	for _, c := range cs.byID {
+		if fmt.Sprintf("%d", c.ID) == chirpID && cs.visible(c) {
+			return cs.render(c), true
+		}
+	}
+	return ResChirp{}, false
+}
+
+// GetChirps queries the database for all chirps listed for the viewer,
+// returning them in a slice.
+func (s *Service) GetChirps(viewerID int, sortAsc bool) []ResChirp {
+	chirps := []ResChirp{}
+	cs, err := s.loadChirps(viewerID)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, c := range cs.byID {
+		if cs.inTimeline(c) {
+			chirps = append(chirps, cs.render(c))
+		}
+	}
 
 	if sortAsc {
 		slices.SortFunc(chirps, sortChirpsAsc)
@@ -137,17 +541,199 @@ func (s *Service) GetChirps(sortAsc bool) []db.Chirp {
 }
 
 // GetChirpsByAuthor queries the database for all chirps authored by the user
-// with the given ID, returning them in a slice.
-func (s *Service) GetChirpsByAuthor(authorID int, sortAsc bool) []db.Chirp {
-	chirps := []db.Chirp{}
-	allChirps, err := s.dbConn.GetChirps()
+// with the given ID and listed for the viewer, returning them in a slice.
+func (s *Service) GetChirpsByAuthor(viewerID int, authorID int, sortAsc bool) []ResChirp {
+	chirps := []ResChirp{}
+	cs, err := s.loadChirps(viewerID)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, c := range cs.byID {
+		if c.AuthorID == authorID && cs.inTimeline(c) {
+			chirps = append(chirps, cs.render(c))
+		}
+	}
+
+	if sortAsc {
+		slices.SortFunc(chirps, sortChirpsAsc)
+	} else {
+		slices.SortFunc(chirps, sortChirpsDesc)
+	}
+	return chirps
+}
+
+// CreateChirp adds a new chirp to the database after checking its length,
+// running it through the content filter and extracting mentions and hashtags,
+// attaching the given uploaded media and poll (if any). Chirpy Red users get a
+// higher length limit. An empty visibility makes the chirp public.
+func (s *Service) CreateChirp(authorID int, body string, mediaIDs []int, visibility string, poll *NewPoll) (ResChirp, error) {
+	if poll != nil {
+		err := validatePoll(poll)
+		if err != nil {
+			return ResChirp{}, err
+		}
+	}
+
+	newChirp, checks, err := s.prepareChirp(authorID, body, mediaIDs, visibility)
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	newChirp, err = s.dbConn.CreateChirp(newChirp)
+	if errors.Is(err, db.ErrInvalidMedia) {
+		return ResChirp{}, ErrInvalidMedia
+	}
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	if poll != nil {
+		_, err = s.dbConn.CreatePoll(newChirp.ID, poll.Options, poll.ExpiresAt)
+		if err != nil {
+			return ResChirp{}, err
+		}
+	}
+
+	return s.chirpPosted(newChirp, checks)
+}
+
+// chirpChecks holds what the content filter and spam scoring found in a new
+// regular chirp
+type chirpChecks struct {
+	flagged []string
+	spam    spam.Result
+}
+
+// prepareChirp validates, filters and scores the body of a new regular chirp,
+// returning it with its entities parsed along with what the checks found.
+func (s *Service) prepareChirp(authorID int, body string, mediaIDs []int, visibility string) (db.Chirp, chirpChecks, error) {
+	if len(mediaIDs) > maxMediaPerChirp {
+		return db.Chirp{}, chirpChecks{}, ErrInvalidMedia
+	}
+
+	visibility, err := validateVisibility(visibility)
+	if err != nil {
+		return db.Chirp{}, chirpChecks{}, err
+	}
+
+	return s.checkChirp(db.Chirp{
+		AuthorID:   authorID,
+		Body:       body,
+		Kind:       db.KindChirp,
+		MediaIDs:   mediaIDs,
+		Visibility: visibility,
+	})
+}
+
+// checkChirp runs the body of a new or edited chirp through the length check,
+// the content filter and spam scoring, returning the chirp with its filtered
+// body and entities parsed along with what the checks found. Chirps scored as
+// likely spam are held, and those scored as spam are rejected with their
+// verdict recorded.
+func (s *Service) checkChirp(c db.Chirp) (db.Chirp, chirpChecks, error) {
+	err := s.checkLength(c.AuthorID, c.Body)
+	if err != nil {
+		return db.Chirp{}, chirpChecks{}, err
+	}
+
+	body, flagged, err := s.filterBody(c.Body)
+	if err != nil {
+		return db.Chirp{}, chirpChecks{}, err
+	}
+
+	c.Body = body
+	c, err = s.withEntities(c)
+	if err != nil {
+		return db.Chirp{}, chirpChecks{}, err
+	}
+
+	res, err := s.scoreChirp(c)
+	if err != nil {
+		return db.Chirp{}, chirpChecks{}, err
+	}
+	if res.Verdict == spam.VerdictReject {
+		err = s.recordSpamVerdict(c, res)
+		if err != nil {
+			return db.Chirp{}, chirpChecks{}, err
+		}
+		return db.Chirp{}, chirpChecks{}, ErrSpam
+	}
+	c.Held = res.Verdict == spam.VerdictHold
+	return c, chirpChecks{flagged: flagged, spam: res}, nil
+}
+
+// chirpPosted flags a newly saved chirp with a body and records its spam
+// verdict if needed, and lets everything else know about it unless it is held
+func (s *Service) chirpPosted(newChirp db.Chirp, checks chirpChecks) (ResChirp, error) {
+	err := s.flagChirp(newChirp, checks.flagged)
+	if err != nil {
+		return ResChirp{}, err
+	}
+	err = s.recordSpamVerdict(newChirp, checks.spam)
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	cs, err := s.loadChirps(newChirp.AuthorID)
+	if err != nil {
+		return ResChirp{}, err
+	}
+	if !newChirp.Held {
+		s.chirpCreated(newChirp, cs)
+	}
+	return cs.render(newChirp), nil
+}
+
+// chirpCreated updates the in-memory trends, search index and subscribed
+// clients with a newly created chirp, and notifies the users it involves. cs
+// must contain the new chirp and the chirp it refers to, if any.
+func (s *Service) chirpCreated(c db.Chirp, cs chirpSet) {
+	s.trackHashtags(c, cs.shadowBanned[c.AuthorID] || cs.protected[c.AuthorID])
+	s.indexChirp(c)
+	s.notifyChirp(c, cs.byID)
+	s.publishChirpEvent(stream.EventChirp, c, func(cs chirpSet) any { return cs.render(c) })
+}
+
+// withEntities parses the mentions and hashtags in the chirp body and attaches
+// them to the chirp. Users blocked either way by the author can't be
+// mentioned.
+func (s *Service) withEntities(c db.Chirp) (db.Chirp, error) {
+	users, err := s.dbConn.GetUsers()
+	if err != nil {
+		return c, err
+	}
+
+	blocked, _, err := s.relationsOf(c.AuthorID)
+	if err != nil {
+		return c, err
+	}
+	users = slices.DeleteFunc(users, func(u db.User) bool { return blocked[u.ID] })
+
+	c.Mentions, c.Hashtags = parseEntities(c.Body, users)
+	return c, nil
+}
+
+// GetChirpsByHashtag queries the database for all chirps tagged with the given
+// hashtag (case-insensitive) and listed for the viewer, returning them in a
+// slice.
+func (s *Service) GetChirpsByHashtag(viewerID int, tag string, sortAsc bool) []ResChirp {
+	chirps := []ResChirp{}
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	cs, err := s.loadChirps(viewerID)
 	if err != nil {
 		panic(err)
 	}
 
-	for _, c := range allChirps {
-		if c.AuthorID == authorID {
-			chirps = append(chirps, c)
+	for _, c := range cs.byID {
+		if !cs.inTimeline(c) {
+			continue
+		}
+		for _, h := range c.Hashtags {
+			if h.Tag == tag {
+				chirps = append(chirps, cs.render(c))
+				break
+			}
 		}
 	}
 
@@ -159,33 +745,122 @@ func (s *Service) GetChirpsByAuthor(authorID int, sortAsc bool) []db.Chirp {
 	return chirps
 }
 
-// CreateChirp adds a new chirp to the database after cleaning profane words.
-// Note that the 140-character validation happens at the handler level because
-// it is considered a bad request to send a longer chirp.
-func (s *Service) CreateChirp(authorID int, body string) (db.Chirp, error) {
-	inFields := strings.Fields(body)
-	for i, f := range inFields {
-		lower := strings.ToLower(f)
-		if lower == "kerfuffle" || lower == "sharbert" || lower == "fornax" {
-			inFields[i] = "****"
+// originalOf looks up the chirp to be rechirped, quoted or replied to by the
+// given user. Rechirps are resolved to the chirp they share so that sharing
+// always points to the original content. Chirps by users blocked either way
+// can't be interacted with.
+func (s *Service) originalOf(userID int, chirpID string) (db.Chirp, chirpSet, error) {
+	cs, err := s.loadChirps(userID)
+	if err != nil {
+		return db.Chirp{}, cs, err
+	}
+
+	id, err := strconv.Atoi(chirpID)
+	if err != nil {
+		return db.Chirp{}, cs, ErrChirpNotFound
+	}
+
+	original, ok := cs.byID[id]
+	if !ok {
+		return db.Chirp{}, cs, ErrChirpNotFound
+	}
+
+	if original.Kind == db.KindRechirp {
+		original, ok = cs.byID[original.OriginalID]
+		if !ok {
+			return db.Chirp{}, cs, ErrChirpNotFound
 		}
 	}
-	cleaned := strings.Join(inFields, " ")
+	if cs.blocked[original.AuthorID] {
+		return db.Chirp{}, cs, ErrBlocked
+	}
+	if !cs.canSee(original) {
+		return db.Chirp{}, cs, ErrChirpNotFound
+	}
+	return original, cs, nil
+}
+
+// Rechirp shares the chirp of the given ID on behalf of the given user. A user
+// can only rechirp a chirp once.
+func (s *Service) Rechirp(authorID int, chirpID string) (ResChirp, error) {
+	original, cs, err := s.originalOf(authorID, chirpID)
+	if err != nil {
+		return ResChirp{}, err
+	}
+	if !cs.isPublic(original) {
+		return ResChirp{}, ErrNotRechirpable
+	}
 
-	return s.dbConn.CreateChirp(authorID, cleaned)
+	for _, c := range cs.byID {
+		if c.Kind == db.KindRechirp && c.AuthorID == authorID && c.OriginalID == original.ID {
+			return ResChirp{}, ErrAlreadyRechirped
+		}
+	}
+
+	newChirp, err := s.dbConn.CreateChirp(db.Chirp{
+		AuthorID:   authorID,
+		Kind:       db.KindRechirp,
+		OriginalID: original.ID,
+		Mentions:   []db.Mention{},
+		Hashtags:   []db.Hashtag{},
+	})
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	cs.byID[newChirp.ID] = newChirp
+	s.chirpCreated(newChirp, cs)
+	return cs.render(newChirp), nil
 }
 
-// CreateUser adds a new user to the database after hashing the given password.
-func (s *Service) CreateUser(email string, password string) (db.User, error) {
+// QuoteChirp shares the chirp of the given ID along with the user's own
+// commentary, which is filtered the same way as a regular chirp.
+func (s *Service) QuoteChirp(authorID int, chirpID string, body string) (ResChirp, error) {
+	original, _, err := s.originalOf(authorID, chirpID)
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	newChirp, checks, err := s.checkChirp(db.Chirp{
+		AuthorID:   authorID,
+		Body:       body,
+		Kind:       db.KindQuote,
+		OriginalID: original.ID,
+	})
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	newChirp, err = s.dbConn.CreateChirp(newChirp)
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	return s.chirpPosted(newChirp, checks)
+}
+
+// CreateUser adds a new user to the database after checking the email and
+// password and hashing the password. If no handle is given, one is derived
+// from the email.
+func (s *Service) CreateUser(email string, password string, handle string) (db.User, error) {
+	email, err := validateEmail(email)
+	if err != nil {
+		return db.User{}, err
+	}
+	err = validatePassword(password, email)
+	if err != nil {
+		return db.User{}, err
+	}
+
 	users, err := s.dbConn.GetUsers()
 	if err != nil {
 		return db.User{}, err
 	}
 
-	for _, c := range users {
-		if c.Email == email {
-			return db.User{}, fmt.Errorf("email %v already exists", email)
-		}
+	if handle == "" {
+		handle = defaultHandle(email, users)
+	} else if err := validateHandle(handle); err != nil {
+		return db.User{}, err
 	}
 
 	hPassword, err := bcrypt.GenerateFromPassword([]byte(password), 10)
@@ -193,14 +868,28 @@ func (s *Service) CreateUser(email string, password string) (db.User, error) {
 		return db.User{}, err
 	}
 
-	return s.dbConn.CreateUser(email, string(hPassword))
+	newUser, err := s.dbConn.CreateUser(email, string(hPassword), handle)
+	if errors.Is(err, db.ErrHandleTaken) {
+		return db.User{}, ErrHandleTaken
+	}
+	if errors.Is(err, db.ErrEmailTaken) {
+		return db.User{}, ErrEmailTaken
+	}
+	if err != nil {
+		return db.User{}, err
+	}
+
+	s.indexUser(newUser)
+	return newUser, nil
 }
 
-// Login simply matches the email and password against the ones currently
-// stored at the database. It returns the the user data with access and refresh
-// JWTs.
-func (s *Service) Login(email string, password string) (ResUserDataT, error) {
+// Login simply matches the email, ignoring its case, and password against the
+// ones currently stored at the database. It returns the the user data with
+// access and refresh JWTs. Every attempt is audited along with the IP it came
+// from.
+func (s *Service) Login(email string, password string, ip string) (ResUserDataT, error) {
 	var outUser ResUserDataT
+	email = strings.TrimSpace(email)
 
 	users, err := s.dbConn.GetUsers()
 	if err != nil {
@@ -209,9 +898,14 @@ func (s *Service) Login(email string, password string) (ResUserDataT, error) {
 	}
 
 	for _, u := range users {
-		emailMatch := u.Email == email
+		emailMatch := strings.EqualFold(u.Email, email)
 		passMatch := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil
 		if emailMatch && passMatch {
+			if isSuspended(u) {
+				s.record(AuditLoginFailed, u.ID, ip, userTarget(u.ID), map[string]string{"reason": "suspended"})
+				return outUser, &SuspensionError{Reason: u.SuspensionReason, Until: u.SuspendedUntil}
+			}
+
 			accessStr, err := generateAccess(u.ID)
 			if err != nil {
 				return outUser, err
@@ -224,13 +918,18 @@ func (s *Service) Login(email string, password string) (ResUserDataT, error) {
 
 			outUser.ID = u.ID
 			outUser.Email = u.Email
+			outUser.Handle = u.Handle
 			outUser.IsChirpyRed = u.IsChirpyRed
+			outUser.MaxChirpLength = s.maxChirpLength(u.IsChirpyRed)
+			outUser.Role = roleOf(u)
 			outUser.Token = accessStr
 			outUser.RefreshToken = refreshStr
+			s.record(AuditLogin, u.ID, ip, userTarget(u.ID), nil)
 			return outUser, nil
 		}
 	}
 
+	s.record(AuditLoginFailed, 0, ip, "", map[string]string{"email": email})
 	return outUser, fmt.Errorf("user doesn't exist")
 }
 
@@ -281,7 +980,7 @@ func generateRefresh(userID int) (refreshStr string, err error) {
 }
 
 // AuthorizeUser takes a bearer token and returns the integer ID of the user
-// that owns the token
+// that owns the token. Tokens of deleted or suspended users are rejected.
 func (s *Service) AuthorizeUser(bearer string) (int, error) {
 	claims := &jwt.RegisteredClaims{}
 	keyfunc := func(toke *jwt.Token) (interface{}, error) {
@@ -310,32 +1009,104 @@ func (s *Service) AuthorizeUser(bearer string) (int, error) {
 		return 0, err
 	}
 
-	return userID, err
+	issuedAt, err := token.Claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return 0, fmt.Errorf("missing issue time")
+	}
+
+	return userID, s.checkStatus(userID, issuedAt.Time)
 }
 
-// UpdateUser updates the email and password of the user whose ID is provided
-// in the first argument
-func (s *Service) UpdateUser(id int, newEmail string, newPassword string) (ResUserData, error) {
-	hNewPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), 10)
+// UpdateUser updates the fields of the given update for the user whose ID is
+// provided in the first argument, confirming the user's current password
+// first if the email or password changes. A password change revokes the
+// user's sessions and closes their streams, so the response carries new
+// tokens for the current one.
+// The change is audited along with the IP it came from.
+func (s *Service) UpdateUser(id int, update UserUpdate, ip string) (ResUserUpdate, error) {
+	oldUser, err := s.dbConn.GetUser(id)
 	if err != nil {
-		return ResUserData{}, err
+		return ResUserUpdate{}, err
 	}
 
-	updatedUser, err := s.dbConn.UpdateUser(id, newEmail, string(hNewPassword))
-	if err != nil {
-		return ResUserData{}, err
+	if update.Email != nil || update.Password != nil {
+		err = bcrypt.CompareHashAndPassword([]byte(oldUser.Password), []byte(update.CurrentPassword))
+		if err != nil {
+			return ResUserUpdate{}, ErrWrongPassword
+		}
 	}
 
-	out := ResUserData{
-		ID:    updatedUser.ID,
-		Email: updatedUser.Email,
+	newEmail := ""
+	if update.Email != nil {
+		newEmail, err = validateEmail(*update.Email)
+		if err != nil {
+			return ResUserUpdate{}, err
+		}
+	}
+
+	hNewPassword := []byte{}
+	if update.Password != nil {
+		err = validatePassword(*update.Password, oldUser.Email, newEmail)
+		if err != nil {
+			return ResUserUpdate{}, err
+		}
+		hNewPassword, err = bcrypt.GenerateFromPassword([]byte(*update.Password), 10)
+		if err != nil {
+			return ResUserUpdate{}, err
+		}
+	}
+
+	updatedUser := oldUser
+	if newEmail != "" || len(hNewPassword) > 0 {
+		updatedUser, err = s.dbConn.UpdateUser(id, newEmail, string(hNewPassword))
+		if errors.Is(err, db.ErrEmailTaken) {
+			return ResUserUpdate{}, ErrEmailTaken
+		}
+		if err != nil {
+			return ResUserUpdate{}, err
+		}
+		s.indexUser(updatedUser)
+
+		details := map[string]string{}
+		if len(hNewPassword) > 0 {
+			details["password_changed"] = "true"
+			s.hub.Disconnect(id)
+		}
+		if oldUser.Email != updatedUser.Email {
+			details["old_email"] = oldUser.Email
+			details["new_email"] = updatedUser.Email
+		}
+		s.record(AuditUserUpdated, id, ip, userTarget(id), details)
+	}
+
+	out := ResUserUpdate{
+		ResUserData: ResUserData{
+			ID:             updatedUser.ID,
+			Email:          updatedUser.Email,
+			Handle:         updatedUser.Handle,
+			IsChirpyRed:    updatedUser.IsChirpyRed,
+			MaxChirpLength: s.maxChirpLength(updatedUser.IsChirpyRed),
+			Role:           roleOf(updatedUser),
+		},
+	}
+	if len(hNewPassword) > 0 {
+		out.Token, err = generateAccess(id)
+		if err != nil {
+			return out, err
+		}
+		out.RefreshToken, err = generateRefresh(id)
+		if err != nil {
+			return out, err
+		}
 	}
 
 	return out, nil
 }
 
 // AuthorizeRefresh checks if a refresh token is valid, which means it is 1)
-// not revoked 2) a valid JWT and 3) issued as a refresh token.
+// not revoked 2) a valid JWT 3) issued as a refresh token and 4) owned by a
+// user who isn't suspended. For suspended users, the error is a
+// SuspensionError.
 func (s *Service) AuthorizeRefresh(bearer string) (userID int, err error) {
 	revokedTokens, err := s.dbConn.GetRevokedTokens()
 	if err != nil {
@@ -374,7 +1145,12 @@ func (s *Service) AuthorizeRefresh(bearer string) (userID int, err error) {
 		return 0, err
 	}
 
-	return userID, err
+	issuedAt, err := token.Claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return 0, fmt.Errorf("missing issue time")
+	}
+
+	return userID, s.checkStatus(userID, issuedAt.Time)
 }
 
 // Refresh generates a new access token for the given user ID
@@ -388,21 +1164,88 @@ func (s *Service) Refresh(userID int) (ResRefresh, error) {
 	return newAccess, err
 }
 
-// Revoke stores the given bearer token in the database
-func (s *Service) Revoke(bearer string) error {
+// Revoke stores the given bearer token of the user in the database
+func (s *Service) Revoke(userID int, bearer string, ip string) error {
 	err := s.dbConn.AddRevokedToken(bearer, time.Now())
-	return err
+	if err != nil {
+		return err
+	}
+	s.record(AuditTokenRevoked, userID, ip, userTarget(userID), nil)
+	return nil
+}
+
+// DeleteChirp deletes the chirp of a given ID on behalf of the user. Users can
+// delete their own chirps, and moderators can delete any chirp. The deletion
+// is audited along with the IP it came from.
+func (s *Service) DeleteChirp(userID int, chirpID string, ip string) error {
+	u, err := s.dbConn.GetUser(userID)
+	if err != nil {
+		return err
+	}
+	if !hasRole(u, db.RoleModerator) {
+		chirp, ok := s.GetChirp(userID, chirpID)
+		if !ok {
+			return ErrChirpNotFound
+		}
+		if chirp.AuthorID != userID {
+			return ErrNotAuthor
+		}
+	}
+
+	chirp, err := s.removeChirp(chirpID)
+	if err != nil {
+		return err
+	}
+	s.record(AuditChirpDeleted, userID, ip, chirpTarget(chirp.ID), map[string]string{
+		"author_id": fmt.Sprint(chirp.AuthorID),
+	})
+	return nil
 }
 
-// DeleteChirp deletes the chrip of a given ID
-func (s *Service) DeleteChirp(chirpID string) error {
-	err := s.dbConn.DeleteChirp(chirpID)
-	return err
+// removeChirp deletes the chirp of a given ID along with its media, and tells
+// the users who could see it. It returns the deleted chirp.
+func (s *Service) removeChirp(chirpID string) (db.Chirp, error) {
+	chirps, err := s.dbConn.GetChirps()
+	if err != nil {
+		return db.Chirp{}, err
+	}
+	i := slices.IndexFunc(chirps, func(c db.Chirp) bool { return strconv.Itoa(c.ID) == chirpID })
+	if i == -1 {
+		return db.Chirp{}, ErrChirpNotFound
+	}
+	chirp := chirps[i]
+
+	mediaList, err := s.dbConn.GetMedia()
+	if err != nil {
+		return db.Chirp{}, err
+	}
+	chirpMedia := []db.Media{}
+	for _, m := range mediaList {
+		if m.ChirpID == chirp.ID {
+			chirpMedia = append(chirpMedia, m)
+		}
+	}
+
+	err = s.dbConn.DeleteChirp(chirpID)
+	if err != nil {
+		return db.Chirp{}, err
+	}
+	s.deleteChirpBlobs(chirpMedia)
+
+	s.trends.Remove(chirp.ID)
+	s.index.RemoveChirp(chirp.ID)
+	s.publishChirpEvent(stream.EventDelete, chirp, deletedEvent(chirp))
+	return chirp, nil
 }
 
 // UpgradeChirpyRed upgrades the user with the given ID for Chirpy Red
-// subscription
-func (s *Service) UpgradeChirpyRed(userID int) error {
+// subscription. Upgrades come from the payment provider's webhook, so they
+// have no actor.
+func (s *Service) UpgradeChirpyRed(userID int, ip string) error {
 	err := s.dbConn.UpgradeChirpyRed(userID)
-	return err
+	if err != nil {
+		return err
+	}
+	s.record(AuditChirpyRedUpgrade, 0, ip, userTarget(userID), nil)
+	return nil
 }
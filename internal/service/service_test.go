@@ -0,0 +1,99 @@
+package service
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+)
+
+// testService returns a service backed by a fresh database
+func testService(t *testing.T) *Service {
+	t.Helper()
+	dbConn, err := db.NewDB(filepath.Join(t.TempDir(), "database.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Service{dbConn: dbConn}
+}
+
+// trendingTags returns the tags trending over the last day
+func trendingTags(s *Service) []string {
+	tags := []string{}
+	for _, trend := range s.GetTrends(10)["24h"] {
+		tags = append(tags, trend.Tag)
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+func TestTrendsExcludeHiddenAuthors(t *testing.T) {
+	t.Setenv("TRENDS_REFRESH", "1ns")
+	s := testService(t)
+
+	user := func(email string, handle string) db.User {
+		u, err := s.dbConn.CreateUser(email, "hash", handle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	chirp := func(authorID int, tag string, visibility string, held bool) {
+		_, err := s.dbConn.CreateChirp(db.Chirp{
+			AuthorID:   authorID,
+			Body:       "#" + tag,
+			Hashtags:   []db.Hashtag{{Start: 0, End: len(tag) + 1, Tag: tag}},
+			Visibility: visibility,
+			Held:       held,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plain := user("plain@example.com", "plain")
+	protected := user("protected@example.com", "protected")
+	banned := user("banned@example.com", "banned")
+	_, err := s.dbConn.SetProtected(protected.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.dbConn.SetShadowBanned(banned.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chirp(plain.ID, "public", db.VisibilityPublic, false)
+	chirp(plain.ID, "followers", db.VisibilityFollowers, false)
+	chirp(plain.ID, "held", db.VisibilityPublic, true)
+	chirp(protected.ID, "protected", db.VisibilityPublic, false)
+	chirp(banned.ID, "banned", db.VisibilityPublic, false)
+
+	s.InitTrends()
+	if got := trendingTags(s); !slices.Equal(got, []string{"public"}) {
+		t.Fatalf("got %v, want [public]", got)
+	}
+
+	// Changing an author's status retracks their chirps
+	banned, err = s.dbConn.SetShadowBanned(banned.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.retrackChirps(banned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err = s.dbConn.SetProtected(plain.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.retrackChirps(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := trendingTags(s); !slices.Equal(got, []string{"banned"}) {
+		t.Errorf("after retracking got %v, want [banned]", got)
+	}
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+)
+
+// ResFollowRequest holds a pending follow request to be used by handlers in
+// HTTP responses
+type ResFollowRequest struct {
+	ID        int       `json:"id"`
+	Follower  ResAuthor `json:"follower"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Follow makes the follower follow the user with the given ID. Protected
+// accounts have to approve their followers, in which case a follow request is
+// made instead and true is returned.
+func (s *Service) Follow(followerID int, followeeID int) (bool, error) {
+	if followerID == followeeID {
+		return false, ErrSelfFollow
+	}
+
+	followee, err := s.dbConn.GetUser(followeeID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return false, ErrUserNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+
+	blocked, err := s.isBlocked(followerID, followeeID)
+	if err != nil {
+		return false, err
+	}
+	if blocked {
+		return false, ErrBlocked
+	}
+
+	if followee.Protected {
+		_, created, err := s.dbConn.CreateFollowRequest(followerID, followeeID)
+		if err != nil {
+			return false, err
+		}
+		if created {
+			s.notify(followeeID, followerID, db.NotifyFollowRequest, 0)
+		}
+		return created || !s.follows(followerID, followeeID), nil
+	}
+
+	_, created, err := s.dbConn.CreateFollow(followerID, followeeID)
+	if err != nil {
+		return false, err
+	}
+
+	if created {
+		s.notify(followeeID, followerID, db.NotifyFollow, 0)
+	}
+	return false, nil
+}
+
+// follows reports whether the follower follows the followee
+func (s *Service) follows(followerID int, followeeID int) bool {
+	follows, err := s.dbConn.GetFollows()
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(follows, func(f db.Follow) bool {
+		return f.FollowerID == followerID && f.FolloweeID == followeeID
+	})
+}
+
+// Unfollow makes the follower stop following the user with the given ID, or
+// cancels their pending follow request
+func (s *Service) Unfollow(followerID int, followeeID int) error {
+	requests, err := s.dbConn.GetFollowRequests()
+	if err != nil {
+		return err
+	}
+	for _, r := range requests {
+		if r.FollowerID == followerID && r.FolloweeID == followeeID {
+			return s.dbConn.DeleteFollowRequest(r.ID)
+		}
+	}
+	return s.dbConn.DeleteFollow(followerID, followeeID)
+}
+
+// GetFollowRequests returns the pending follow requests to the user, oldest
+// first
+func (s *Service) GetFollowRequests(userID int) ([]ResFollowRequest, error) {
+	out := []ResFollowRequest{}
+	requests, err := s.dbConn.GetFollowRequests()
+	if err != nil {
+		return out, err
+	}
+	slices.SortFunc(requests, func(a, b db.FollowRequest) int { return a.ID - b.ID })
+
+	for _, r := range requests {
+		if r.FolloweeID != userID {
+			continue
+		}
+		follower, err := s.dbConn.GetUser(r.FollowerID)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, ResFollowRequest{
+			ID:        r.ID,
+			Follower:  toResAuthor(follower),
+			CreatedAt: r.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+// requestTo returns the follow request of the given ID if it was made to the
+// user
+func (s *Service) requestTo(userID int, requestID int) (db.FollowRequest, error) {
+	requests, err := s.dbConn.GetFollowRequests()
+	if err != nil {
+		return db.FollowRequest{}, err
+	}
+	i := slices.IndexFunc(requests, func(r db.FollowRequest) bool { return r.ID == requestID })
+	if i == -1 || requests[i].FolloweeID != userID {
+		return db.FollowRequest{}, ErrFollowRequestNotFound
+	}
+	return requests[i], nil
+}
+
+// ApproveFollowRequest lets the requester of one of the user's follow
+// requests follow them
+func (s *Service) ApproveFollowRequest(userID int, requestID int) error {
+	_, err := s.requestTo(userID, requestID)
+	if err != nil {
+		return err
+	}
+	return s.approveFollowRequests(userID, []int{requestID})
+}
+
+// approveFollowRequests turns follow requests to the user into follows and
+// notifies the new followers
+func (s *Service) approveFollowRequests(userID int, ids []int) error {
+	follows, err := s.dbConn.ApproveFollowRequests(ids)
+	if err != nil {
+		return err
+	}
+	for _, f := range follows {
+		s.notify(f.FollowerID, userID, db.NotifyFollowApproved, 0)
+	}
+	return nil
+}
+
+// RejectFollowRequest removes one of the user's follow requests
+func (s *Service) RejectFollowRequest(userID int, requestID int) error {
+	_, err := s.requestTo(userID, requestID)
+	if err != nil {
+		return err
+	}
+	return s.dbConn.DeleteFollowRequest(requestID)
+}
+
+// SetProtected sets whether the user's account is protected. Protected
+// accounts' chirps don't count towards trends, and pending follow requests are
+// approved when an account stops being protected.
+func (s *Service) SetProtected(userID int, protected bool) error {
+	u, err := s.dbConn.SetProtected(userID, protected)
+	if err != nil {
+		return err
+	}
+	err = s.retrackChirps(u)
+	if err != nil || protected {
+		return err
+	}
+
+	requests, err := s.GetFollowRequests(userID)
+	if err != nil || len(requests) == 0 {
+		return err
+	}
+	ids := []int{}
+	for _, r := range requests {
+		ids = append(ids, r.ID)
+	}
+	return s.approveFollowRequests(userID, ids)
+}
+
+// Like makes the user like the chirp of the given ID
+func (s *Service) Like(userID int, chirpID string) error {
+	cs, err := s.loadChirps(userID)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(chirpID)
+	if err != nil {
+		return ErrChirpNotFound
+	}
+	chirp, ok := cs.byID[id]
+	if !ok {
+		return ErrChirpNotFound
+	}
+	if !cs.visible(chirp) {
+		return ErrBlocked
+	}
+
+	_, created, err := s.dbConn.CreateLike(userID, id)
+	if err != nil {
+		return err
+	}
+
+	if created {
+		s.notify(chirp.AuthorID, userID, db.NotifyLike, id)
+	}
+	return nil
+}
+
+// Unlike removes the user's like from the chirp of the given ID
+func (s *Service) Unlike(userID int, chirpID string) error {
+	id, err := strconv.Atoi(chirpID)
+	if err != nil {
+		return ErrChirpNotFound
+	}
+	return s.dbConn.DeleteLike(userID, id)
+}
+
+// Reply adds a new chirp in reply to the chirp of the given ID. The reply is
+// filtered and parsed the same way as a regular chirp.
+func (s *Service) Reply(authorID int, chirpID string, body string) (ResChirp, error) {
+	original, _, err := s.originalOf(authorID, chirpID)
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	newChirp, checks, err := s.checkChirp(db.Chirp{
+		AuthorID:   authorID,
+		Body:       body,
+		Kind:       db.KindReply,
+		OriginalID: original.ID,
+	})
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	newChirp, err = s.dbConn.CreateChirp(newChirp)
+	if err != nil {
+		return ResChirp{}, err
+	}
+
+	return s.chirpPosted(newChirp, checks)
+}
+
+// GetReplies queries the database for all replies to the chirp of the given
+// ID listed for the viewer, oldest first
+func (s *Service) GetReplies(viewerID int, chirpID string) ([]ResChirp, error) {
+	replies := []ResChirp{}
+	cs, err := s.loadChirps(viewerID)
+	if err != nil {
+		return replies, err
+	}
+
+	id, err := strconv.Atoi(chirpID)
+	if err != nil {
+		return replies, ErrChirpNotFound
+	}
+	if c, ok := cs.byID[id]; !ok || !cs.visible(c) {
+		return replies, ErrChirpNotFound
+	}
+
+	for _, c := range cs.byID {
+		if c.Kind == db.KindReply && c.OriginalID == id && cs.inTimeline(c) {
+			replies = append(replies, cs.render(c))
+		}
+	}
+
+	slices.SortFunc(replies, sortChirpsAsc)
+	return replies, nil
+}
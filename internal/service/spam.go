@@ -0,0 +1,205 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/spam"
+)
+
+// Spam review actions. Approving a held chirp posts it and removing it
+// deletes it.
+const (
+	SpamApprove = "approve"
+	SpamRemove  = "remove"
+)
+
+// InitSpam creates the spam scorer from the JSON config file at SPAM_CONFIG,
+// or from the default config if that isn't set
+func (s *Service) InitSpam() {
+	config := spam.DefaultConfig
+	if path := os.Getenv("SPAM_CONFIG"); path != "" {
+		var err error
+		config, err = spam.LoadConfig(path)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var err error
+	s.spam, err = spam.New(config)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// scoreChirp scores a new or edited chirp for spam against its author's other
+// chirps and the age of their account
+func (s *Service) scoreChirp(c db.Chirp) (spam.Result, error) {
+	author, err := s.dbConn.GetUser(c.AuthorID)
+	if err != nil {
+		return spam.Result{}, err
+	}
+	chirps, err := s.dbConn.GetChirps()
+	if err != nil {
+		return spam.Result{}, err
+	}
+
+	in := spam.Input{
+		Chirp: spam.Chirp{
+			Body:      c.Body,
+			Mentions:  len(c.Mentions),
+			CreatedAt: time.Now(),
+		},
+		Recent:         []spam.Chirp{},
+		AccountCreated: author.CreatedAt,
+	}
+	for _, prev := range chirps {
+		if prev.AuthorID != c.AuthorID || prev.Kind == db.KindRechirp || prev.ID == c.ID {
+			continue
+		}
+		in.Recent = append(in.Recent, spam.Chirp{
+			Body:      prev.Body,
+			Mentions:  len(prev.Mentions),
+			CreatedAt: prev.CreatedAt,
+		})
+	}
+	return s.spam.Score(in), nil
+}
+
+// recordSpamVerdict saves the spam verdict of a chirp that fired any signals,
+// so moderators can see how the rules play out. Rejected new chirps are
+// recorded without an ID.
+func (s *Service) recordSpamVerdict(c db.Chirp, res spam.Result) error {
+	if len(res.Signals) == 0 {
+		return nil
+	}
+
+	signals := []db.SpamSignal{}
+	for _, sig := range res.Signals {
+		signals = append(signals, db.SpamSignal{Name: sig.Name, Points: sig.Points, Detail: sig.Detail})
+	}
+	_, err := s.dbConn.CreateSpamVerdict(db.SpamVerdict{
+		AuthorID: c.AuthorID,
+		ChirpID:  c.ID,
+		Body:     c.Body,
+		Score:    res.Score,
+		Verdict:  res.Verdict,
+		Signals:  signals,
+	})
+	return err
+}
+
+// GetSpamConfig returns the rules chirps are scored by to a moderator
+func (s *Service) GetSpamConfig(moderatorID int) (spam.Config, error) {
+	err := s.RequireRole(moderatorID, db.RoleModerator)
+	if err != nil {
+		return spam.Config{}, err
+	}
+	return s.spam.Config(), nil
+}
+
+// GetSpamVerdicts returns the spam verdicts with the given verdict, or all of
+// them if no verdict is given, newest first. reviewed filters verdicts by
+// whether they were reviewed if it isn't nil. Only moderators can see them.
+func (s *Service) GetSpamVerdicts(moderatorID int, verdict string, reviewed *bool) ([]db.SpamVerdict, error) {
+	out := []db.SpamVerdict{}
+	err := s.RequireRole(moderatorID, db.RoleModerator)
+	if err != nil {
+		return out, err
+	}
+
+	switch verdict {
+	case "", spam.VerdictAllow, spam.VerdictHold, spam.VerdictReject:
+	default:
+		return out, ErrInvalidVerdict
+	}
+
+	verdicts, err := s.dbConn.GetSpamVerdicts()
+	if err != nil {
+		return out, err
+	}
+	slices.SortFunc(verdicts, func(a, b db.SpamVerdict) int { return b.ID - a.ID })
+
+	for _, v := range verdicts {
+		if (verdict != "" && v.Verdict != verdict) || (reviewed != nil && *reviewed != (v.Review != "")) {
+			continue
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// GetSpamVerdict returns the spam verdict of the given ID to a moderator
+func (s *Service) GetSpamVerdict(moderatorID int, verdictID int) (db.SpamVerdict, error) {
+	err := s.RequireRole(moderatorID, db.RoleModerator)
+	if err != nil {
+		return db.SpamVerdict{}, err
+	}
+
+	verdicts, err := s.dbConn.GetSpamVerdicts()
+	if err != nil {
+		return db.SpamVerdict{}, err
+	}
+	i := slices.IndexFunc(verdicts, func(v db.SpamVerdict) bool { return v.ID == verdictID })
+	if i == -1 {
+		return db.SpamVerdict{}, ErrVerdictNotFound
+	}
+	return verdicts[i], nil
+}
+
+// ReviewSpamVerdict approves or removes a held chirp on behalf of a
+// moderator. Approved chirps are posted as if they were just created.
+func (s *Service) ReviewSpamVerdict(moderatorID int, verdictID int, action string, ip string) (db.SpamVerdict, error) {
+	v, err := s.GetSpamVerdict(moderatorID, verdictID)
+	if err != nil {
+		return v, err
+	}
+	if v.Verdict != spam.VerdictHold {
+		return v, ErrInvalidSpamReview
+	}
+
+	review := ""
+	switch action {
+	case SpamApprove:
+		review = db.ReviewApproved
+	case SpamRemove:
+		review = db.ReviewRemoved
+	default:
+		return v, ErrInvalidSpamReview
+	}
+
+	v, err = s.dbConn.ReviewSpamVerdict(v.ID, review, moderatorID)
+	if errors.Is(err, db.ErrVerdictReviewed) {
+		return v, ErrVerdictReviewed
+	}
+	if err != nil {
+		return v, err
+	}
+	s.record(AuditSpamReview, moderatorID, ip, chirpTarget(v.ChirpID), map[string]string{
+		"verdict_id": fmt.Sprint(v.ID),
+		"review":     review,
+	})
+
+	if review == db.ReviewRemoved {
+		_, err = s.removeChirp(strconv.Itoa(v.ChirpID))
+		if errors.Is(err, ErrChirpNotFound) {
+			return v, nil
+		}
+		return v, err
+	}
+
+	cs, err := s.loadChirps(v.AuthorID)
+	if err != nil {
+		return v, err
+	}
+	if c, ok := cs.byID[v.ChirpID]; ok {
+		s.chirpCreated(c, cs)
+	}
+	return v, nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+	"github.com/wipdev-tech/chirpy/internal/stream"
+)
+
+// InitStream creates the hub used to push events to connected clients. The
+// hub keeps the last 1000 events for resuming clients, and drops clients that
+// fall more than 100 events behind.
+func (s *Service) InitStream() {
+	s.hub = stream.NewHub(1000, 100)
+}
+
+// Subscribe registers a client of the given user for streamed events,
+// returning the events published after lastEventID that the client missed
+func (s *Service) Subscribe(userID int, lastEventID uint64) (*stream.Subscriber, []stream.Event) {
+	return s.hub.Subscribe(userID, lastEventID)
+}
+
+// Unsubscribe removes a client from the streamed events
+func (s *Service) Unsubscribe(sub *stream.Subscriber) {
+	s.hub.Unsubscribe(sub)
+}
+
+// publishChirpEvent publishes an event about the given chirp to its author
+// and the followers who'd have it listed in their timeline, so blocks, mutes
+// and visibility apply to the original of a rechirp too. render builds the
+// event data from each recipient's chirp set, so originals embedded into
+// rechirps and quotes (and poll votes) are only shown to the recipients
+// allowed to see them. Recipients getting the same data share an event.
+func (s *Service) publishChirpEvent(eventType string, c db.Chirp, render func(cs chirpSet) any) {
+	cs, err := s.loadChirps(c.AuthorID)
+	if err != nil {
+		fmt.Println("Error loading chirps:", err)
+		return
+	}
+	// Deleted chirps are gone from the database but still need delivering
+	cs.byID[c.ID] = c
+
+	follows, err := s.dbConn.GetFollows()
+	if err != nil {
+		fmt.Println("Error getting follows:", err)
+		return
+	}
+	followerIDs := []int{}
+	for _, f := range follows {
+		if f.FolloweeID == c.AuthorID {
+			followerIDs = append(followerIDs, f.FollowerID)
+		}
+	}
+	sets, err := s.viewerSets(cs, followerIDs)
+	if err != nil {
+		fmt.Println("Error loading chirps:", err)
+		return
+	}
+
+	type delivery struct {
+		data       any
+		recipients []int
+	}
+	deliveries := []delivery{}
+	byData := map[string]int{}
+	deliver := func(vs chirpSet) {
+		data := render(vs)
+		key, err := json.Marshal(data)
+		if err != nil {
+			fmt.Println("Error encoding event:", err)
+			return
+		}
+		i, ok := byData[string(key)]
+		if !ok {
+			i = len(deliveries)
+			byData[string(key)] = i
+			deliveries = append(deliveries, delivery{data: data})
+		}
+		deliveries[i].recipients = append(deliveries[i].recipients, vs.viewerID)
+	}
+
+	deliver(cs)
+	for _, id := range followerIDs {
+		vs := sets[id]
+		if vs.inTimeline(c) {
+			deliver(vs)
+		}
+	}
+	for _, d := range deliveries {
+		s.hub.Publish(eventType, d.data, d.recipients)
+	}
+}
+
+// deletedEvent renders the data of an event about a deleted chirp, which is
+// the same for everyone
+func deletedEvent(c db.Chirp) func(chirpSet) any {
+	return func(chirpSet) any { return map[string]int{"id": c.ID} }
+}
+
+// viewerSets derives the chirp sets of the given viewers from another
+// viewer's set, sharing its chirps, users and media and loading what depends
+// on the viewer only once for all of them
+func (s *Service) viewerSets(cs chirpSet, viewerIDs []int) (map[int]chirpSet, error) {
+	blocks, err := s.dbConn.GetBlocks()
+	if err != nil {
+		return nil, err
+	}
+	mutes, err := s.dbConn.GetMutes()
+	if err != nil {
+		return nil, err
+	}
+	follows, err := s.dbConn.GetFollows()
+	if err != nil {
+		return nil, err
+	}
+	polls, err := s.dbConn.GetPolls()
+	if err != nil {
+		return nil, err
+	}
+	votes, err := s.dbConn.GetVotes()
+	if err != nil {
+		return nil, err
+	}
+
+	sets := map[int]chirpSet{}
+	for _, id := range viewerIDs {
+		vs := cs
+		vs.viewerID = id
+		vs.blocked, vs.muted = relationsIn(blocks, mutes, id)
+		vs.following = map[int]bool{}
+		for _, f := range follows {
+			if f.FollowerID == id {
+				vs.following[f.FolloweeID] = true
+			}
+		}
+		vs.polls = pollsFor(polls, votes, id)
+		sets[id] = vs
+	}
+	return sets, nil
+}
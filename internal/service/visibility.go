@@ -0,0 +1,49 @@
+package service
+
+import (
+	"slices"
+
+	"github.com/wipdev-tech/chirpy/internal/db"
+)
+
+// validateVisibility returns the visibility to save a chirp with, defaulting
+// to public
+func validateVisibility(visibility string) (string, error) {
+	switch visibility {
+	case "":
+		return db.VisibilityPublic, nil
+	case db.VisibilityPublic, db.VisibilityFollowers, db.VisibilityMentioned:
+		return visibility, nil
+	}
+	return "", ErrInvalidVisibility
+}
+
+// canSee reports whether the viewer (0 for anonymous viewers) can see the
+// chirp given whether they follow its author and whether the author's
+// account is protected. Public chirps of protected accounts are treated as
+// followers-only, and held chirps are only visible to their author. Blocks
+// are checked separately.
+func canSee(c db.Chirp, viewerID int, follows bool, protected bool) bool {
+	if viewerID != 0 && c.AuthorID == viewerID {
+		return true
+	}
+	if c.Held {
+		return false
+	}
+
+	mentioned := viewerID != 0 && slices.ContainsFunc(c.Mentions, func(m db.Mention) bool {
+		return m.UserID == viewerID
+	})
+	switch c.Visibility {
+	case db.VisibilityMentioned:
+		return mentioned
+	case db.VisibilityFollowers:
+		return follows || mentioned
+	}
+	return !protected || follows || mentioned
+}
+
+// isPublic reports whether a chirp can be seen by everyone
+func (cs chirpSet) isPublic(c db.Chirp) bool {
+	return !cs.shadowBanned[c.AuthorID] && canSee(c, 0, false, cs.protected[c.AuthorID])
+}
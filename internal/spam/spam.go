@@ -0,0 +1,314 @@
+// Package spam scores new chirps for how likely they are to be spam. Each
+// signal that fires (near-duplicates of the author's recent chirps, too many
+// links, mention floods and new accounts posting too fast) adds points, and
+// the total decides whether a chirp is allowed, held for review or rejected.
+package spam
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wipdev-tech/chirpy/internal/text"
+)
+
+// Verdicts. Allowed chirps are posted as usual, held chirps are only visible
+// to their author until a moderator reviews them and rejected chirps aren't
+// saved at all.
+const (
+	VerdictAllow  = "allow"
+	VerdictHold   = "hold"
+	VerdictReject = "reject"
+)
+
+// Signals that add to a chirp's score
+const (
+	SignalDuplicate = "duplicate"
+	SignalLinks     = "links"
+	SignalMentions  = "mentions"
+	SignalVelocity  = "velocity"
+)
+
+// ErrInvalidConfig is returned for configs with thresholds that aren't
+// positive or a hold score that isn't below the reject score
+var ErrInvalidConfig = errors.New("invalid spam config")
+
+// Duration is a time.Duration written as a string like "24h" in configs
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Config holds the scoring rules. Chirps scoring HoldScore or more are held
+// for review, and those scoring RejectScore or more are rejected.
+type Config struct {
+	HoldScore   int `json:"hold_score"`
+	RejectScore int `json:"reject_score"`
+
+	// Every chirp by the same author within DuplicateWindow whose words are
+	// at least DuplicateSimilarity (0-1) alike adds DuplicatePoints
+	DuplicateWindow     Duration `json:"duplicate_window"`
+	DuplicateSimilarity float64  `json:"duplicate_similarity"`
+	DuplicatePoints     int      `json:"duplicate_points"`
+
+	// More than MaxLinks links, or more than one link making up over
+	// MaxLinkDensity (0-1) of the words, adds LinkPoints
+	MaxLinks       int     `json:"max_links"`
+	MaxLinkDensity float64 `json:"max_link_density"`
+	LinkPoints     int     `json:"link_points"`
+
+	// Every mention over MaxMentions adds MentionPoints
+	MaxMentions   int `json:"max_mentions"`
+	MentionPoints int `json:"mention_points"`
+
+	// Accounts younger than NewAccountAge posting more than NewAccountRate
+	// chirps within an hour add VelocityPoints
+	NewAccountAge  Duration `json:"new_account_age"`
+	NewAccountRate int      `json:"new_account_rate"`
+	VelocityPoints int      `json:"velocity_points"`
+}
+
+// DefaultConfig is used when no config file is given, and fills in the
+// settings a config file leaves out
+var DefaultConfig = Config{
+	HoldScore:           50,
+	RejectScore:         100,
+	DuplicateWindow:     Duration(24 * time.Hour),
+	DuplicateSimilarity: 0.8,
+	DuplicatePoints:     40,
+	MaxLinks:            3,
+	MaxLinkDensity:      0.5,
+	LinkPoints:          30,
+	MaxMentions:         5,
+	MentionPoints:       15,
+	NewAccountAge:       Duration(24 * time.Hour),
+	NewAccountRate:      10,
+	VelocityPoints:      40,
+}
+
+// LoadConfig reads a config from a JSON file of the same shape as Config
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	return config, err
+}
+
+// Chirp is a chirp as far as scoring is concerned. Mentions is the number of
+// users it mentions.
+type Chirp struct {
+	Body      string
+	Mentions  int
+	CreatedAt time.Time
+}
+
+// Input is everything a new chirp is scored on: the chirp, the author's
+// earlier chirps and when the author's account was created (zero if
+// unknown)
+type Input struct {
+	Chirp          Chirp
+	Recent         []Chirp
+	AccountCreated time.Time
+}
+
+// Signal is a signal that fired for a chirp along with the points it added
+// and a short explanation
+type Signal struct {
+	Name   string `json:"name"`
+	Points int    `json:"points"`
+	Detail string `json:"detail"`
+}
+
+// Result is the outcome of scoring a chirp
+type Result struct {
+	Score   int
+	Verdict string
+	Signals []Signal
+}
+
+// Scorer scores chirps according to a config
+type Scorer struct {
+	config Config
+}
+
+// New creates a scorer with the given config
+func New(config Config) (*Scorer, error) {
+	if config.HoldScore <= 0 || config.RejectScore <= config.HoldScore ||
+		config.DuplicateWindow <= 0 || config.NewAccountAge <= 0 ||
+		config.DuplicateSimilarity <= 0 || config.DuplicateSimilarity > 1 ||
+		config.MaxLinkDensity <= 0 || config.MaxLinkDensity > 1 {
+		return nil, ErrInvalidConfig
+	}
+	return &Scorer{config: config}, nil
+}
+
+// Config returns the scorer's config
+func (sc *Scorer) Config() Config {
+	return sc.config
+}
+
+// Score scores a new chirp
+func (sc *Scorer) Score(in Input) Result {
+	res := Result{Verdict: VerdictAllow, Signals: []Signal{}}
+	for _, signal := range []Signal{
+		sc.duplicates(in),
+		sc.links(in.Chirp),
+		sc.mentions(in.Chirp),
+		sc.velocity(in),
+	} {
+		if signal.Points > 0 {
+			res.Score += signal.Points
+			res.Signals = append(res.Signals, signal)
+		}
+	}
+
+	switch {
+	case res.Score >= sc.config.RejectScore:
+		res.Verdict = VerdictReject
+	case res.Score >= sc.config.HoldScore:
+		res.Verdict = VerdictHold
+	}
+	return res
+}
+
+// duplicates scores the recent chirps the new one is a near-duplicate of.
+// Bodies without any tokens, such as emoji-only ones, have nothing to compare
+// by similarity, so they only count as duplicates of the exact same body.
+func (sc *Scorer) duplicates(in Input) Signal {
+	since := in.Chirp.CreatedAt.Add(-time.Duration(sc.config.DuplicateWindow))
+	tokens := tokenSet(in.Chirp.Body)
+	body := normalizeBody(in.Chirp.Body)
+
+	count := 0
+	for _, c := range in.Recent {
+		if c.CreatedAt.Before(since) {
+			continue
+		}
+		if len(tokens) == 0 {
+			if body != "" && normalizeBody(c.Body) == body {
+				count++
+			}
+			continue
+		}
+		if similarity(tokens, tokenSet(c.Body)) >= sc.config.DuplicateSimilarity {
+			count++
+		}
+	}
+	return Signal{
+		Name:   SignalDuplicate,
+		Points: count * sc.config.DuplicatePoints,
+		Detail: fmt.Sprintf("%d near-duplicate chirps in the last %s", count, time.Duration(sc.config.DuplicateWindow)),
+	}
+}
+
+// links scores chirps that are mostly links
+func (sc *Scorer) links(c Chirp) Signal {
+	words := strings.Fields(c.Body)
+	links := 0
+	for _, w := range words {
+		if isLink(w) {
+			links++
+		}
+	}
+
+	signal := Signal{Name: SignalLinks}
+	if links == 0 {
+		return signal
+	}
+	density := float64(links) / float64(len(words))
+	if links > sc.config.MaxLinks || (links > 1 && density > sc.config.MaxLinkDensity) {
+		signal.Points = sc.config.LinkPoints
+	}
+	signal.Detail = fmt.Sprintf("%d links in %d words", links, len(words))
+	return signal
+}
+
+// mentions scores chirps mentioning too many users
+func (sc *Scorer) mentions(c Chirp) Signal {
+	return Signal{
+		Name:   SignalMentions,
+		Points: max(c.Mentions-sc.config.MaxMentions, 0) * sc.config.MentionPoints,
+		Detail: fmt.Sprintf("%d mentions", c.Mentions),
+	}
+}
+
+// velocity scores new accounts posting more chirps than they're allowed to
+// within an hour, the new chirp included
+func (sc *Scorer) velocity(in Input) Signal {
+	signal := Signal{Name: SignalVelocity}
+	if in.AccountCreated.IsZero() || in.Chirp.CreatedAt.Sub(in.AccountCreated) >= time.Duration(sc.config.NewAccountAge) {
+		return signal
+	}
+
+	since := in.Chirp.CreatedAt.Add(-time.Hour)
+	count := 1
+	for _, c := range in.Recent {
+		if !c.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	if count > sc.config.NewAccountRate {
+		signal.Points = sc.config.VelocityPoints
+	}
+	signal.Detail = fmt.Sprintf("%d chirps in the last hour from a new account", count)
+	return signal
+}
+
+// isLink reports whether a word looks like a link
+func isLink(word string) bool {
+	word = strings.ToLower(word)
+	return strings.HasPrefix(word, "http://") || strings.HasPrefix(word, "https://") || strings.HasPrefix(word, "www.")
+}
+
+// tokenSet returns the distinct tokens of a chirp body
+func tokenSet(body string) map[string]bool {
+	set := map[string]bool{}
+	for _, t := range text.Tokenize(body) {
+		set[t] = true
+	}
+	return set
+}
+
+// normalizeBody folds a chirp body and collapses its whitespace
+func normalizeBody(body string) string {
+	return strings.Join(strings.Fields(text.Fold(body)), " ")
+}
+
+// similarity returns the Jaccard similarity of two token sets: the share of
+// tokens in either set that are in both. A set without any tokens isn't
+// similar to anything.
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range a {
+		if b[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
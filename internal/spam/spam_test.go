@@ -0,0 +1,208 @@
+package spam
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestScorer(t *testing.T) *Scorer {
+	t.Helper()
+	sc, err := New(DefaultConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sc
+}
+
+// recent returns chirps with the given bodies posted over the last minutes
+func recent(bodies ...string) []Chirp {
+	chirps := []Chirp{}
+	for i, body := range bodies {
+		chirps = append(chirps, Chirp{Body: body, CreatedAt: now.Add(-time.Duration(i+1) * time.Minute)})
+	}
+	return chirps
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		wantErr error
+	}{
+		{"default", func(c *Config) {}, nil},
+		{"zero hold score", func(c *Config) { c.HoldScore = 0 }, ErrInvalidConfig},
+		{"reject not above hold", func(c *Config) { c.RejectScore = c.HoldScore }, ErrInvalidConfig},
+		{"zero duplicate window", func(c *Config) { c.DuplicateWindow = 0 }, ErrInvalidConfig},
+		{"similarity above 1", func(c *Config) { c.DuplicateSimilarity = 1.5 }, ErrInvalidConfig},
+		{"zero link density", func(c *Config) { c.MaxLinkDensity = 0 }, ErrInvalidConfig},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig
+		tt.change(&config)
+		_, err := New(config)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: New() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	sc := newTestScorer(t)
+	links := "www.a.com www.b.com www.c.com www.d.com"
+
+	tests := []struct {
+		name        string
+		in          Input
+		wantScore   int
+		wantVerdict string
+	}{
+		{
+			name:        "plain chirp",
+			in:          Input{Chirp: Chirp{Body: "hello world"}},
+			wantScore:   0,
+			wantVerdict: VerdictAllow,
+		},
+		{
+			name:        "one duplicate",
+			in:          Input{Chirp: Chirp{Body: "Buy my course now"}, Recent: recent("buy my course now!")},
+			wantScore:   40,
+			wantVerdict: VerdictAllow,
+		},
+		{
+			name:        "two duplicates",
+			in:          Input{Chirp: Chirp{Body: "buy my course now"}, Recent: recent("buy my course now", "buy my course now")},
+			wantScore:   80,
+			wantVerdict: VerdictHold,
+		},
+		{
+			name:        "three duplicates",
+			in:          Input{Chirp: Chirp{Body: "buy my course now"}, Recent: recent("buy my course now", "buy my course now", "buy my course now")},
+			wantScore:   120,
+			wantVerdict: VerdictReject,
+		},
+		{
+			name: "duplicate outside the window",
+			in: Input{Chirp: Chirp{Body: "buy my course now"}, Recent: []Chirp{
+				{Body: "buy my course now", CreatedAt: now.Add(-25 * time.Hour)},
+			}},
+			wantScore:   0,
+			wantVerdict: VerdictAllow,
+		},
+		{
+			name:        "different words",
+			in:          Input{Chirp: Chirp{Body: "good morning everyone"}, Recent: recent("good night everyone", "good morning")},
+			wantScore:   0,
+			wantVerdict: VerdictAllow,
+		},
+		{
+			name:        "different emoji-only chirps",
+			in:          Input{Chirp: Chirp{Body: "🎉🎉"}, Recent: recent("😂", "❤️", "!!!")},
+			wantScore:   0,
+			wantVerdict: VerdictAllow,
+		},
+		{
+			name:        "emoji-only chirp after word chirps",
+			in:          Input{Chirp: Chirp{Body: "🎉"}, Recent: recent("hello", "world")},
+			wantScore:   0,
+			wantVerdict: VerdictAllow,
+		},
+		{
+			name:        "word chirp after emoji-only chirps",
+			in:          Input{Chirp: Chirp{Body: "hello"}, Recent: recent("🎉", "😂")},
+			wantScore:   0,
+			wantVerdict: VerdictAllow,
+		},
+		{
+			name:        "repeated emoji-only chirp",
+			in:          Input{Chirp: Chirp{Body: "🎉 🎉"}, Recent: recent("🎉  🎉", "🎉 🎉")},
+			wantScore:   80,
+			wantVerdict: VerdictHold,
+		},
+		{
+			name:        "too many links",
+			in:          Input{Chirp: Chirp{Body: links + " and some words here"}},
+			wantScore:   30,
+			wantVerdict: VerdictAllow,
+		},
+		{
+			name:        "mostly links",
+			in:          Input{Chirp: Chirp{Body: "see https://a.com https://b.com"}},
+			wantScore:   30,
+			wantVerdict: VerdictAllow,
+		},
+		{
+			name:        "one link",
+			in:          Input{Chirp: Chirp{Body: "https://a.com"}},
+			wantScore:   0,
+			wantVerdict: VerdictAllow,
+		},
+		{
+			name:        "links and a duplicate",
+			in:          Input{Chirp: Chirp{Body: links}, Recent: recent(links)},
+			wantScore:   70,
+			wantVerdict: VerdictHold,
+		},
+		{
+			name:        "mention flood",
+			in:          Input{Chirp: Chirp{Body: "hi all", Mentions: 9}},
+			wantScore:   60,
+			wantVerdict: VerdictHold,
+		},
+		{
+			name:        "mentions at the limit",
+			in:          Input{Chirp: Chirp{Body: "hi all", Mentions: 5}},
+			wantScore:   0,
+			wantVerdict: VerdictAllow,
+		},
+		{
+			name: "new account posting too fast",
+			in: Input{
+				Chirp:          Chirp{Body: "chirp"},
+				Recent:         recent(strings.Split("a b c d e f g h i j", " ")...),
+				AccountCreated: now.Add(-time.Hour),
+			},
+			wantScore:   40,
+			wantVerdict: VerdictAllow,
+		},
+		{
+			name: "old account posting fast",
+			in: Input{
+				Chirp:          Chirp{Body: "chirp"},
+				Recent:         recent(strings.Split("a b c d e f g h i j", " ")...),
+				AccountCreated: now.Add(-48 * time.Hour),
+			},
+			wantScore:   0,
+			wantVerdict: VerdictAllow,
+		},
+	}
+	for _, tt := range tests {
+		tt.in.Chirp.CreatedAt = now
+		res := sc.Score(tt.in)
+		if res.Score != tt.wantScore || res.Verdict != tt.wantVerdict {
+			t.Errorf("%s: Score() = %d (%s), want %d (%s); signals %+v",
+				tt.name, res.Score, res.Verdict, tt.wantScore, tt.wantVerdict, res.Signals)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"a b c d", "a b c d", 1},
+		{"a b c d", "a b", 0.5},
+		{"a b", "c d", 0},
+		{"", "", 0},
+		{"a", "", 0},
+	}
+	for _, tt := range tests {
+		if got := similarity(tokenSet(tt.a), tokenSet(tt.b)); got != tt.want {
+			t.Errorf("similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Package stream has the in-process pub/sub hub used to push events (new,
+// edited and deleted chirps, notifications and direct messages) to connected
+// clients, along with a minimal WebSocket implementation for serving them.
+package stream
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// WriteTimeout is how long a write to a client may take before the client is
+// considered stalled and disconnected
+const WriteTimeout = 10 * time.Second
+
+// Event types
+const (
+	EventChirp        = "chirp"
+	EventEdit         = "edit"
+	EventDelete       = "delete"
+	EventNotification = "notification"
+	EventMessage      = "message"
+	EventRead         = "read"
+)
+
+// Event is a message published to the hub. Only the subscribers whose user ID
+// is among the recipients receive it.
+type Event struct {
+	ID         uint64 `json:"id"`
+	Type       string `json:"type"`
+	Data       any    `json:"data"`
+	recipients []int
+}
+
+// Subscriber is a single connected client. Events are delivered on the
+// Events channel; Done is closed when the subscriber is dropped for falling
+// too far behind, in which case the client should reconnect and resume from
+// the last event ID it received, or when its user is disconnected.
+type Subscriber struct {
+	UserID int
+	Events chan Event
+	Done   chan struct{}
+}
+
+// Hub fans out published events to subscribers. It keeps a backlog of recent
+// events so that reconnecting clients can resume where they left off.
+type Hub struct {
+	mux         *sync.Mutex
+	lastID      uint64
+	backlog     []Event
+	backlogSize int
+	bufferSize  int
+	subs        map[*Subscriber]struct{}
+}
+
+// NewHub creates a hub keeping up to backlogSize recent events, and buffering
+// up to bufferSize events for every subscriber before dropping it
+func NewHub(backlogSize int, bufferSize int) *Hub {
+	return &Hub{
+		mux:         &sync.Mutex{},
+		backlogSize: backlogSize,
+		bufferSize:  bufferSize,
+		subs:        map[*Subscriber]struct{}{},
+	}
+}
+
+// Publish sends an event to the subscribers of the given users. Publishing
+// never blocks: subscribers whose buffer is full are dropped.
+func (h *Hub) Publish(eventType string, data any, recipients []int) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	h.lastID++
+	e := Event{
+		ID:         h.lastID,
+		Type:       eventType,
+		Data:       data,
+		recipients: recipients,
+	}
+
+	h.backlog = append(h.backlog, e)
+	if len(h.backlog) > h.backlogSize {
+		h.backlog = h.backlog[len(h.backlog)-h.backlogSize:]
+	}
+
+	for sub := range h.subs {
+		if !slices.Contains(recipients, sub.UserID) {
+			continue
+		}
+		select {
+		case sub.Events <- e:
+		default:
+			h.drop(sub)
+		}
+	}
+}
+
+// Subscribe registers a subscriber for the given user. If lastEventID is not
+// zero, the backlogged events for the user published after it are returned so
+// that the client can catch up before reading from the subscriber.
+func (h *Hub) Subscribe(userID int, lastEventID uint64) (*Subscriber, []Event) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	sub := &Subscriber{
+		UserID: userID,
+		Events: make(chan Event, h.bufferSize),
+		Done:   make(chan struct{}),
+	}
+	h.subs[sub] = struct{}{}
+
+	missed := []Event{}
+	if lastEventID == 0 {
+		return sub, missed
+	}
+	for _, e := range h.backlog {
+		if e.ID > lastEventID && slices.Contains(e.recipients, userID) {
+			missed = append(missed, e)
+		}
+	}
+	return sub, missed
+}
+
+// Unsubscribe removes a subscriber from the hub
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if _, ok := h.subs[sub]; ok {
+		h.drop(sub)
+	}
+}
+
+// Disconnect drops every subscriber of the given user, such as when they can
+// no longer sign in
+func (h *Hub) Disconnect(userID int) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	for sub := range h.subs {
+		if sub.UserID == userID {
+			h.drop(sub)
+		}
+	}
+}
+
+// drop removes a subscriber and signals it to disconnect. The caller must hold
+// the lock.
+func (h *Hub) drop(sub *Subscriber) {
+	delete(h.subs, sub)
+	close(sub.Done)
+}
@@ -0,0 +1,149 @@
+package stream
+
+import (
+	"slices"
+	"testing"
+)
+
+// received returns the events waiting on a subscriber without blocking
+func received(sub *Subscriber) []Event {
+	events := []Event{}
+	for {
+		select {
+		case e := <-sub.Events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func isDone(sub *Subscriber) bool {
+	select {
+	case <-sub.Done:
+		return true
+	default:
+		return false
+	}
+}
+
+func eventIDs(events []Event) []uint64 {
+	ids := []uint64{}
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestPublishFansOut(t *testing.T) {
+	h := NewHub(10, 10)
+	phone, _ := h.Subscribe(1, 0)
+	laptop, _ := h.Subscribe(1, 0)
+	other, _ := h.Subscribe(2, 0)
+	third, _ := h.Subscribe(3, 0)
+
+	h.Publish(EventChirp, "to 1", []int{1})
+	h.Publish(EventChirp, "to 1 and 2", []int{1, 2})
+
+	tests := []struct {
+		name string
+		sub  *Subscriber
+		want []uint64
+	}{
+		{"first subscriber of user 1", phone, []uint64{1, 2}},
+		{"second subscriber of user 1", laptop, []uint64{1, 2}},
+		{"user 2", other, []uint64{2}},
+		{"user 3", third, []uint64{}},
+	}
+	for _, tt := range tests {
+		if got := eventIDs(received(tt.sub)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got events %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSubscribeResumes(t *testing.T) {
+	h := NewHub(2, 10)
+	h.Publish(EventChirp, "a", []int{1})
+	h.Publish(EventChirp, "b", []int{2})
+	h.Publish(EventChirp, "c", []int{1})
+	h.Publish(EventChirp, "d", []int{1, 2})
+
+	tests := []struct {
+		name        string
+		userID      int
+		lastEventID uint64
+		want        []uint64
+	}{
+		{"new connection", 1, 0, []uint64{}},
+		{"missed some", 1, 2, []uint64{3, 4}},
+		{"only own events", 2, 3, []uint64{4}},
+		{"trimmed from the backlog", 2, 1, []uint64{4}},
+		{"up to date", 1, 4, []uint64{}},
+	}
+	for _, tt := range tests {
+		_, missed := h.Subscribe(tt.userID, tt.lastEventID)
+		if got := eventIDs(missed); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Subscribe(%d, %d) missed %v, want %v", tt.name, tt.userID, tt.lastEventID, got, tt.want)
+		}
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	h := NewHub(10, 10)
+	sub, _ := h.Subscribe(1, 0)
+	stays, _ := h.Subscribe(1, 0)
+
+	h.Unsubscribe(sub)
+	if !isDone(sub) {
+		t.Fatal("Done isn't closed after unsubscribing")
+	}
+	// Unsubscribing twice mustn't close Done again
+	h.Unsubscribe(sub)
+
+	h.Publish(EventChirp, "after", []int{1})
+	if got := received(sub); len(got) != 0 {
+		t.Errorf("unsubscribed subscriber got %d events, want 0", len(got))
+	}
+	if got := received(stays); len(got) != 1 {
+		t.Errorf("remaining subscriber got %d events, want 1", len(got))
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	h := NewHub(10, 10)
+	phone, _ := h.Subscribe(1, 0)
+	laptop, _ := h.Subscribe(1, 0)
+	other, _ := h.Subscribe(2, 0)
+
+	h.Disconnect(1)
+	if !isDone(phone) || !isDone(laptop) {
+		t.Error("subscribers of the disconnected user weren't dropped")
+	}
+	if isDone(other) {
+		t.Error("subscriber of another user was dropped")
+	}
+	// A dropped subscriber can still be unsubscribed by its connection
+	h.Unsubscribe(phone)
+}
+
+func TestPublishDropsSlowSubscriber(t *testing.T) {
+	h := NewHub(10, 2)
+	slow, _ := h.Subscribe(1, 0)
+	fast, _ := h.Subscribe(2, 0)
+
+	for i := 0; i < 3; i++ {
+		h.Publish(EventChirp, "x", []int{1, 2})
+		received(fast)
+	}
+
+	if !isDone(slow) {
+		t.Error("subscriber with a full buffer wasn't dropped")
+	}
+	if got := eventIDs(received(slow)); !slices.Equal(got, []uint64{1, 2}) {
+		t.Errorf("slow subscriber got events %v, want [1 2]", got)
+	}
+	if isDone(fast) {
+		t.Error("subscriber keeping up was dropped")
+	}
+}
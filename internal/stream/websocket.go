@@ -0,0 +1,206 @@
+package stream
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is the fixed GUID from RFC 6455 used to compute the
+// handshake's accept key
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// maxControlPayload is the largest payload a client frame may have. The
+// server only expects control frames from clients, which are at most 125
+// bytes.
+const maxControlPayload = 125
+
+// Conn is a server-side WebSocket connection. Only text frames are sent; any
+// data frames received from the client are ignored.
+type Conn struct {
+	conn   net.Conn
+	rw     *bufio.ReadWriter
+	mux    *sync.Mutex
+	closed chan struct{}
+	once   *sync.Once
+}
+
+// Upgrade performs the WebSocket handshake and takes over the underlying
+// connection. The returned connection starts reading client frames in the
+// background; Closed is closed when the client goes away.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		!strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade") {
+		return nil, errors.New("not a websocket handshake")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("missing websocket key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection can't be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	_, err = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err == nil {
+		err = rw.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c := &Conn{
+		conn:   conn,
+		rw:     rw,
+		mux:    &sync.Mutex{},
+		closed: make(chan struct{}),
+		once:   &sync.Once{},
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// Closed returns a channel that is closed once the connection is closed
+func (c *Conn) Closed() <-chan struct{} {
+	return c.closed
+}
+
+// WriteText sends a text message to the client
+func (c *Conn) WriteText(msg []byte) error {
+	return c.writeFrame(opText, msg)
+}
+
+// Ping sends a ping to the client, which serves as a heartbeat
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// Close sends a close frame and closes the underlying connection
+func (c *Conn) Close() {
+	c.once.Do(func() {
+		_ = c.writeFrame(opClose, nil)
+		c.conn.Close()
+		close(c.closed)
+	})
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+	if err != nil {
+		return err
+	}
+
+	header := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(len(payload)))
+	}
+
+	_, err = c.rw.Write(append(header, payload...))
+	if err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// readLoop reads client frames until the connection closes, answering pings
+// and close frames
+func (c *Conn) readLoop() {
+	defer c.Close()
+
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+
+		switch opcode {
+		case opClose:
+			return
+		case opPing:
+			if c.writeFrame(opPong, payload) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *Conn) readFrame() (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c.rw, header); err != nil {
+		return 0, nil, err
+	}
+
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(c.rw, ext); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(c.rw, ext); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	// Clients must mask their frames, and only control frames are expected
+	if !masked || length > maxControlPayload {
+		return 0, nil, errors.New("invalid client frame")
+	}
+
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(c.rw, mask); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return opcode, payload, nil
+}
@@ -0,0 +1,211 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testServer upgrades every request and greets the client with a text frame
+func testServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = c.WriteText([]byte("hello"))
+		<-c.Closed()
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// dial sends a handshake with the given headers and returns the connection
+// along with the server's response
+func dial(t *testing.T, srv *httptest.Server, headers string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	err = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: chirpy\r\n%s\r\n", headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, br, res
+}
+
+// upgrade completes a handshake and reads the greeting
+func upgrade(t *testing.T, srv *httptest.Server) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, br, res := dial(t, srv, "Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n")
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d, want 101", res.StatusCode)
+	}
+
+	opcode, payload := readServerFrame(t, br)
+	if opcode != opText || string(payload) != "hello" {
+		t.Fatalf("greeting = %#x %q, want text frame \"hello\"", opcode, payload)
+	}
+	return conn, br
+}
+
+// clientFrame builds a client frame, masked unless told otherwise
+func clientFrame(opcode byte, payload []byte, masked bool) []byte {
+	frame := []byte{0x80 | opcode}
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+	if len(payload) < 126 {
+		frame = append(frame, maskBit|byte(len(payload)))
+	} else {
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	if !masked {
+		return append(frame, payload...)
+	}
+
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+// readServerFrame reads an unmasked frame of less than 126 bytes
+func readServerFrame(t *testing.T, br *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		t.Fatal(err)
+	}
+	payload := make([]byte, header[1]&0x7F)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatal(err)
+	}
+	return header[0] & 0x0F, payload
+}
+
+// expectClosed checks that the server sends a close frame and hangs up
+func expectClosed(t *testing.T, br *bufio.Reader) {
+	t.Helper()
+	opcode, _ := readServerFrame(t, br)
+	if opcode != opClose {
+		t.Fatalf("got opcode %#x, want close", opcode)
+	}
+	if _, err := br.ReadByte(); err != io.EOF {
+		t.Fatalf("connection still open after close frame: %v", err)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	srv := testServer(t)
+	_, _, res := dial(t, srv, "Upgrade: websocket\r\n"+
+		"Connection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n")
+
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", res.StatusCode)
+	}
+	// The accept key for this client key is given as an example in RFC 6455
+	if got := res.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+	if !strings.EqualFold(res.Header.Get("Upgrade"), "websocket") {
+		t.Errorf("Upgrade = %q", res.Header.Get("Upgrade"))
+	}
+}
+
+func TestUpgradeRejectsBadHandshake(t *testing.T) {
+	srv := testServer(t)
+	tests := []struct {
+		name    string
+		headers string
+	}{
+		{"plain request", ""},
+		{"missing key", "Upgrade: websocket\r\nConnection: Upgrade\r\n"},
+		{"wrong upgrade", "Upgrade: h2c\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"},
+	}
+	for _, tt := range tests {
+		_, _, res := dial(t, srv, tt.headers)
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", tt.name, res.StatusCode)
+		}
+	}
+}
+
+func TestPingPong(t *testing.T) {
+	srv := testServer(t)
+	conn, br := upgrade(t, srv)
+
+	payload := bytes.Repeat([]byte("p"), maxControlPayload)
+	_, err := conn.Write(clientFrame(opPing, payload, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opcode, got := readServerFrame(t, br)
+	if opcode != opPong || !bytes.Equal(got, payload) {
+		t.Errorf("got %#x with %d bytes, want pong echoing %d bytes", opcode, len(got), len(payload))
+	}
+}
+
+func TestFrameTooLarge(t *testing.T) {
+	srv := testServer(t)
+	conn, br := upgrade(t, srv)
+
+	_, err := conn.Write(clientFrame(opText, bytes.Repeat([]byte("x"), maxControlPayload+1), true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectClosed(t, br)
+}
+
+func TestUnmaskedFrame(t *testing.T) {
+	srv := testServer(t)
+	conn, br := upgrade(t, srv)
+
+	_, err := conn.Write(clientFrame(opPing, []byte("hi"), false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectClosed(t, br)
+}
+
+func TestClientClose(t *testing.T) {
+	srv := testServer(t)
+	conn, br := upgrade(t, srv)
+
+	_, err := conn.Write(clientFrame(opClose, nil, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectClosed(t, br)
+}
@@ -0,0 +1,49 @@
+// Package text has the text normalisation shared by search, the content
+// filter and spam scoring, so that they all agree on when two pieces of text
+// match.
+package text
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// baseLetters maps the Latin letters that carry a stroke or similar mark
+// instead of a combining one, and so don't decompose, to their base letter
+var baseLetters = map[rune]rune{
+	'đ': 'd',
+	'ħ': 'h',
+	'ı': 'i',
+	'ł': 'l',
+	'ø': 'o',
+	'ŧ': 't',
+}
+
+// Fold normalises text for matching. It is decomposed into its compatibility
+// form, so that accented letters lose their accents and variants such as
+// full-width letters and ligatures become plain letters, then lowercased with
+// combining marks dropped.
+func Fold(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if base, ok := baseLetters[r]; ok {
+			r = base
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Tokenize splits text into folded tokens. Anything other than letters and
+// digits separates tokens, so "#go" and "go!" both yield "go".
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(Fold(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,56 @@
+package text
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"Hello", "hello"},
+		{"Café", "cafe"},
+		{"café", "cafe"},
+		{"ÀÉÎÕÜ", "aeiou"},
+		{"Łódź", "lodz"},
+		{"Øresund", "oresund"},
+		{"ﬁne", "fine"},
+		{"ＧＯ", "go"},
+		{"Ⅻ", "xii"},
+		{"straße", "straße"},
+		{"日本語", "日本語"},
+		{"Привет", "привет"},
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.in); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", []string{}},
+		{"   ", []string{}},
+		{"Hello, World!", []string{"hello", "world"}},
+		{"#Go and go!", []string{"go", "and", "go"}},
+		{"@user's café", []string{"user", "s", "cafe"}},
+		{"v1.2 ok", []string{"v1", "2", "ok"}},
+		{"emoji 👍🏽 here", []string{"emoji", "here"}},
+	}
+	for _, tt := range tests {
+		got := Tokenize(tt.in)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package trends keeps track of hashtag usage over sliding time windows and
+// ranks tags by a time-decayed score. The tracker is updated incrementally as
+// chirps are created and deleted, so ranking never needs to scan all chirps.
+package trends
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Windows are the sliding windows trends are computed for, keyed by the name
+// used in API responses
+var Windows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+}
+
+// maxWindow is the longest window; uses older than that are pruned
+const maxWindow = 24 * time.Hour
+
+// Trend is a ranked hashtag in a given window
+type Trend struct {
+	Tag   string  `json:"tag"`
+	Count int     `json:"count"`
+	Score float64 `json:"score"`
+}
+
+// use is a single use of a hashtag by a chirp
+type use struct {
+	chirpID int
+	at      time.Time
+}
+
+// Tracker holds hashtag uses within the longest window along with a cache of
+// the latest ranking, which is recomputed at most once per refresh interval.
+type Tracker struct {
+	mux        *sync.Mutex
+	uses       map[string][]use
+	refresh    time.Duration
+	cached     map[string][]Trend
+	computedAt time.Time
+}
+
+// NewTracker creates an empty tracker whose ranking is cached for the given
+// refresh interval
+func NewTracker(refresh time.Duration) *Tracker {
+	return &Tracker{
+		mux:     &sync.Mutex{},
+		uses:    map[string][]use{},
+		refresh: refresh,
+	}
+}
+
+// Add registers the hashtags of a newly created chirp
+func (t *Tracker) Add(chirpID int, tags []string, at time.Time) {
+	if time.Since(at) > maxWindow {
+		return
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	for _, tag := range tags {
+		t.uses[tag] = append(t.uses[tag], use{chirpID: chirpID, at: at})
+	}
+}
+
+// Remove unregisters the hashtags of a deleted chirp
+func (t *Tracker) Remove(chirpID int) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	for tag, uses := range t.uses {
+		uses = slices.DeleteFunc(uses, func(u use) bool { return u.chirpID == chirpID })
+		if len(uses) == 0 {
+			delete(t.uses, tag)
+		} else {
+			t.uses[tag] = uses
+		}
+	}
+}
+
+// Top returns up to limit trends for every window, highest score first. The
+// result is served from cache unless the refresh interval has passed.
+func (t *Tracker) Top(limit int) map[string][]Trend {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	if t.cached == nil || time.Since(t.computedAt) >= t.refresh {
+		t.cached = t.compute(time.Now())
+		t.computedAt = time.Now()
+	}
+
+	out := map[string][]Trend{}
+	for name, trends := range t.cached {
+		out[name] = trends[:min(limit, len(trends))]
+	}
+	return out
+}
+
+// compute prunes expired uses and ranks tags for every window. A use's weight
+// halves every quarter of the window, so recent uses count the most.
+func (t *Tracker) compute(now time.Time) map[string][]Trend {
+	for tag, uses := range t.uses {
+		uses = slices.DeleteFunc(uses, func(u use) bool { return now.Sub(u.at) > maxWindow })
+		if len(uses) == 0 {
+			delete(t.uses, tag)
+		} else {
+			t.uses[tag] = uses
+		}
+	}
+
+	out := map[string][]Trend{}
+	for name, window := range Windows {
+		halfLife := window / 4
+		trends := []Trend{}
+
+		for tag, uses := range t.uses {
+			trend := Trend{Tag: tag}
+			for _, u := range uses {
+				age := now.Sub(u.at)
+				if age > window {
+					continue
+				}
+				trend.Count++
+				trend.Score += math.Exp2(-float64(age) / float64(halfLife))
+			}
+			if trend.Count > 0 {
+				trends = append(trends, trend)
+			}
+		}
+
+		slices.SortFunc(trends, func(a, b Trend) int {
+			if a.Score != b.Score {
+				if a.Score > b.Score {
+					return -1
+				}
+				return 1
+			}
+			if a.Tag < b.Tag {
+				return -1
+			}
+			return 1
+		})
+		out[name] = trends
+	}
+
+	return out
+}
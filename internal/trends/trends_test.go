@@ -0,0 +1,105 @@
+package trends
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// testTracker returns a tracker with uses registered directly, since Add
+// ignores uses older than the longest window relative to the real clock
+func testTracker(uses map[string][]use) *Tracker {
+	t := NewTracker(time.Minute)
+	t.uses = uses
+	return t
+}
+
+func tags(trends []Trend) []string {
+	out := []string{}
+	for _, trend := range trends {
+		out = append(out, trend.Tag)
+	}
+	return out
+}
+
+func TestCompute(t *testing.T) {
+	tr := testTracker(map[string][]use{
+		"busy":    {{1, now.Add(-time.Minute)}, {2, now.Add(-2 * time.Minute)}, {3, now.Add(-3 * time.Minute)}},
+		"fresh":   {{4, now.Add(-time.Minute)}},
+		"stale":   {{5, now.Add(-50 * time.Minute)}},
+		"daily":   {{6, now.Add(-3 * time.Hour)}, {7, now.Add(-4 * time.Hour)}},
+		"expired": {{8, now.Add(-25 * time.Hour)}},
+	})
+	got := tr.compute(now)
+
+	tests := []struct {
+		window string
+		want   []string
+	}{
+		{"1h", []string{"busy", "fresh", "stale"}},
+		{"24h", []string{"busy", "daily", "fresh", "stale"}},
+	}
+	for _, tt := range tests {
+		if names := tags(got[tt.window]); !slices.Equal(names, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.window, names, tt.want)
+		}
+	}
+
+	if got["1h"][0].Count != 3 {
+		t.Errorf("busy count = %d, want 3", got["1h"][0].Count)
+	}
+	if _, ok := tr.uses["expired"]; ok {
+		t.Error("expired uses weren't pruned")
+	}
+}
+
+func TestComputeTies(t *testing.T) {
+	tr := testTracker(map[string][]use{
+		"b": {{1, now}},
+		"a": {{2, now}},
+	})
+	if got := tags(tr.compute(now)["1h"]); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("got %v, want tied tags in alphabetical order", got)
+	}
+}
+
+func TestAddRemove(t *testing.T) {
+	tr := NewTracker(0)
+	tr.Add(1, []string{"go", "chirpy"}, time.Now())
+	tr.Add(2, []string{"go"}, time.Now())
+	tr.Add(3, []string{"old"}, time.Now().Add(-25*time.Hour))
+
+	if got := tags(tr.Top(10)["24h"]); !slices.Equal(got, []string{"go", "chirpy"}) {
+		t.Fatalf("got %v, want [go chirpy]", got)
+	}
+
+	tr.Remove(1)
+	top := tr.Top(10)["24h"]
+	if got := tags(top); !slices.Equal(got, []string{"go"}) || top[0].Count != 1 {
+		t.Errorf("after removing a chirp got %+v, want go used once", top)
+	}
+}
+
+func TestTopLimit(t *testing.T) {
+	tr := NewTracker(0)
+	tr.Add(1, []string{"a", "b", "c"}, time.Now())
+
+	for _, trends := range tr.Top(2) {
+		if len(trends) != 2 {
+			t.Errorf("got %d trends, want 2", len(trends))
+		}
+	}
+}
+
+func TestTopCache(t *testing.T) {
+	tr := NewTracker(time.Hour)
+	tr.Add(1, []string{"first"}, time.Now())
+	tr.Top(10)
+	tr.Add(2, []string{"second"}, time.Now())
+
+	if got := tags(tr.Top(10)["1h"]); !slices.Equal(got, []string{"first"}) {
+		t.Errorf("got %v, want the cached ranking", got)
+	}
+}
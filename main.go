@@ -2,9 +2,11 @@ package main
 
 import (
 	"net/http"
+	"os"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/joho/godotenv"
+	"github.com/wipdev-tech/chirpy/internal/db"
 	"github.com/wipdev-tech/chirpy/internal/service"
 )
 
@@ -15,38 +17,147 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
 	s.InitDB()
+	s.InitTrends()
+	s.InitSearch()
+	s.InitStream()
+	s.InitMedia()
+	s.InitFilter()
+	s.InitSpam()
+	s.InitLimits()
+	s.InitAudit()
+	s.InitRateLimits()
+	s.StartScheduler()
 
 	appFS := http.FileServer(http.Dir("./static"))
 
 	// API Routes
 	apiRouter := chi.NewRouter()
+	apiRouter.Use(s.MiddlewareRateLimitReads)
 	apiRouter.Get("/healthz", handleHealth)
-	apiRouter.HandleFunc("/reset", handleReset)
+	apiRouter.With(s.MiddlewareRequireRole(db.RoleAdmin)).HandleFunc("/reset", handleReset)
+
+	apiRouter.Post("/media", handleUploadMedia)
 
-	apiRouter.Post("/chirps", handleCreateChirp)
+	apiRouter.With(s.MiddlewareRateLimit(service.LimitChirp)).Post("/chirps", handleCreateChirp)
 	apiRouter.Get("/chirps", handleGetChirps)
 	apiRouter.Get("/chirps/{chirpID}", handleGetChirp)
+	apiRouter.Put("/chirps/{chirpID}", handleEditChirp)
 	apiRouter.Delete("/chirps/{chirpID}", handleDeleteChirp)
+	apiRouter.Get("/chirps/{chirpID}/history", handleGetChirpHistory)
+	apiRouter.With(s.MiddlewareRateLimit(service.LimitChirp)).Post("/chirps/{chirpID}/rechirp", handleRechirp)
+	apiRouter.With(s.MiddlewareRateLimit(service.LimitChirp)).Post("/chirps/{chirpID}/quote", handleQuoteChirp)
+	apiRouter.With(s.MiddlewareRateLimit(service.LimitChirp)).Post("/chirps/{chirpID}/reply", handleReply)
+	apiRouter.Get("/chirps/{chirpID}/replies", handleGetReplies)
+	apiRouter.Post("/chirps/{chirpID}/like", handleLike)
+	apiRouter.Delete("/chirps/{chirpID}/like", handleUnlike)
+	apiRouter.Post("/chirps/{chirpID}/vote", handleVote)
+	apiRouter.Post("/chirps/{chirpID}/bookmark", handleBookmark)
+	apiRouter.Delete("/chirps/{chirpID}/bookmark", handleUnbookmark)
+	apiRouter.Get("/bookmarks", handleGetBookmarks)
+
+	apiRouter.Get("/lists", handleGetLists)
+	apiRouter.Post("/lists", handleCreateList)
+	apiRouter.Get("/lists/{listID}", handleGetList)
+	apiRouter.Put("/lists/{listID}", handleUpdateList)
+	apiRouter.Delete("/lists/{listID}", handleDeleteList)
+	apiRouter.Get("/lists/{listID}/members", handleGetListMembers)
+	apiRouter.Post("/lists/{listID}/members", handleAddListMember)
+	apiRouter.Delete("/lists/{listID}/members/{userID}", handleRemoveListMember)
+	apiRouter.Get("/lists/{listID}/timeline", handleGetListTimeline)
+
+	apiRouter.Get("/drafts", handleGetDrafts)
+	apiRouter.Put("/drafts/{draftID}", handleUpdateDraft)
+	apiRouter.Delete("/drafts/{draftID}", handleDeleteDraft)
+	apiRouter.With(s.MiddlewareRateLimit(service.LimitChirp)).Post("/drafts/{draftID}/publish", handlePublishDraft)
 
-	apiRouter.Post("/login", handleLogin)
-	apiRouter.Post("/users", handleCreateUser)
+	apiRouter.Get("/hashtags/{tag}/chirps", handleGetHashtagChirps)
+	apiRouter.Get("/trends", handleGetTrends)
+	apiRouter.Get("/search", handleSearch)
+
+	apiRouter.With(s.MiddlewareRateLimit(service.LimitLogin)).Post("/login", handleLogin)
+	apiRouter.With(s.MiddlewareRateLimit(service.LimitSignup)).Post("/users", handleCreateUser)
+	apiRouter.Patch("/users", handleUpdateUser)
 	apiRouter.Put("/users", handleUpdateUser)
+	apiRouter.Delete("/users", handleDeleteUser)
+	apiRouter.Get("/users/export", handleExportUser)
+	apiRouter.Put("/users/profile", handleUpdateProfile)
+	apiRouter.Put("/users/privacy", handleUpdatePrivacy)
+	apiRouter.Post("/users/avatar", handleUploadAvatar)
+	apiRouter.Get("/users/{handle}", handleGetProfile)
+	apiRouter.Post("/users/{userID}/follow", handleFollow)
+	apiRouter.Delete("/users/{userID}/follow", handleUnfollow)
+	apiRouter.Post("/users/{userID}/block", handleBlock)
+	apiRouter.Delete("/users/{userID}/block", handleUnblock)
+	apiRouter.Post("/users/{userID}/mute", handleMute)
+	apiRouter.Delete("/users/{userID}/mute", handleUnmute)
+	apiRouter.Get("/blocks", handleGetBlocks)
+	apiRouter.Get("/mutes", handleGetMutes)
+	apiRouter.Get("/follow-requests", handleGetFollowRequests)
+	apiRouter.Post("/follow-requests/{requestID}/approve", handleApproveFollowRequest)
+	apiRouter.Delete("/follow-requests/{requestID}", handleRejectFollowRequest)
+	apiRouter.Post("/reports", handleCreateReport)
+
+	apiRouter.Get("/notifications", handleGetNotifications)
+	apiRouter.Post("/notifications/read", handleReadNotifications)
+	apiRouter.Get("/notifications/preferences", handleGetNotificationPrefs)
+	apiRouter.Put("/notifications/preferences", handleUpdateNotificationPrefs)
 
 	apiRouter.Post("/refresh", handleRefresh)
 	apiRouter.Post("/revoke", handleRevoke)
 
+	apiRouter.Post("/conversations", handleCreateConversation)
+	apiRouter.Get("/conversations", handleGetConversations)
+	apiRouter.Put("/conversations/settings", handleUpdateDMSettings)
+	apiRouter.Get("/conversations/{conversationID}/messages", handleGetMessages)
+	apiRouter.Post("/conversations/{conversationID}/messages", handleSendMessage)
+	apiRouter.Post("/conversations/{conversationID}/read", handleReadConversation)
+
+	apiRouter.Get("/stream", handleStreamSSE)
+	apiRouter.Get("/ws", handleStreamWS)
+
 	apiRouter.Post("/polka/webhooks", handlePolkaWebhook)
 
-	// Admin area routes
+	// Admin area routes. Moderators handle reports and spam, everything else
+	// is for admins only.
 	adminRouter := chi.NewRouter()
-	adminRouter.Get("/metrics", handleMetrics)
-	adminRouter.Get("/metrics/", handleMetrics)
+	adminRouter.Group(func(r chi.Router) {
+		r.Use(s.MiddlewareRequireRole(db.RoleModerator))
+		r.Get("/filter/flags", handleGetContentFlags)
+		r.Get("/reports", handleGetReports)
+		r.Get("/reports/{reportID}", handleGetReport)
+		r.Post("/reports/{reportID}/actions", handleModerateReport)
+		r.Get("/actions", handleGetModActions)
+		r.Get("/spam", handleGetSpamVerdicts)
+		r.Get("/spam/config", handleGetSpamConfig)
+		r.Get("/spam/{verdictID}", handleGetSpamVerdict)
+		r.Post("/spam/{verdictID}/review", handleReviewSpamVerdict)
+		r.Post("/users/{userID}/suspension", handleSuspendUser)
+		r.Delete("/users/{userID}/suspension", handleUnsuspendUser)
+		r.Post("/users/{userID}/shadow-ban", handleShadowBan)
+		r.Delete("/users/{userID}/shadow-ban", handleShadowBan)
+	})
+	adminRouter.Group(func(r chi.Router) {
+		r.Use(s.MiddlewareRequireRole(db.RoleAdmin))
+		r.Get("/metrics", handleMetrics)
+		r.Get("/metrics/", handleMetrics)
+		r.Get("/filter", handleGetFilterRules)
+		r.Put("/filter/{word}", handleSetFilterRule)
+		r.Delete("/filter/{word}", handleDeleteFilterRule)
+		r.Put("/users/{userID}/role", handleSetRole)
+		r.Get("/audit", handleGetAuditLog)
+		r.Get("/audit/verify", handleVerifyAuditLog)
+	})
 
 	// App routes
 	appRouter := chi.NewRouter()
 	appRouter.Handle("/app/*", s.MiddlewareMetricsInc(http.StripPrefix("/app/", appFS)))
 	appRouter.Handle("/app", s.MiddlewareMetricsInc(http.StripPrefix("/app", appFS)))
+	appRouter.Get("/media/*", handleGetMedia)
 	appRouter.Mount("/api", apiRouter)
 	appRouter.Mount("/admin", adminRouter)
 